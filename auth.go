@@ -0,0 +1,99 @@
+package httph
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+type principalContextKey struct{}
+
+// WithPrincipal returns a copy of ctx with the authenticated principal, like a user or API client.
+// Call it from authentication middleware, so later middleware like RequireScope can use it.
+func WithPrincipal(ctx context.Context, principal any) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal set with WithPrincipal, or nil if there is none.
+func PrincipalFromContext(ctx context.Context) any {
+	return ctx.Value(principalContextKey{})
+}
+
+// RequireScope is Middleware to only allow requests with a principal that has all the given scopes, or roles.
+// The scopes of the principal from PrincipalFromContext are given by extract, so it works with any auth scheme.
+// Requests without a principal are unauthenticated and return http.StatusUnauthorized,
+// and requests with a principal missing any of the scopes are unauthorized and return http.StatusForbidden.
+// This means authentication middleware setting the principal must come before RequireScope in the chain.
+func RequireScope(extract func(principal any) []string, scopes ...string) Middleware {
+	if extract == nil {
+		panic("no scope extractor")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := PrincipalFromContext(r.Context())
+			if principal == nil {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			has := map[string]bool{}
+			for _, scope := range extract(principal) {
+				has[scope] = true
+			}
+			for _, scope := range scopes {
+				if !has[scope] {
+					http.Error(w, "missing scope "+scope, http.StatusForbidden)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BasicAuth is Middleware to require HTTP Basic authentication, with credentials checked by check.
+// Requests with missing or invalid credentials return http.StatusUnauthorized, with a WWW-Authenticate header
+// for the realm, so browsers prompt for credentials.
+// On success, the user name is set as the principal, see PrincipalFromContext.
+// Basic authentication sends the password in plain text, so only use it over HTTPS.
+// See StaticCredentials for checking against a single user name and password.
+func BasicAuth(realm string, check func(user, password string) bool) Middleware {
+	if check == nil {
+		panic("no credentials check")
+	}
+	realm = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(realm)
+	challenge := `Basic realm="` + realm + `", charset="UTF-8"`
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, password, ok := r.BasicAuth()
+			if !ok || !check(user, password) {
+				w.Header().Set("WWW-Authenticate", challenge)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), user)))
+		})
+	}
+}
+
+// StaticCredentials returns a check for BasicAuth that only accepts the given user name and password.
+// The comparison is in constant time, to not leak the credentials through timing.
+func StaticCredentials(user, password string) func(user, password string) bool {
+	userHash := sha256.Sum256([]byte(user))
+	passwordHash := sha256.Sum256([]byte(password))
+
+	return func(u, p string) bool {
+		// Hash first, so the comparison doesn't leak the length either
+		uh := sha256.Sum256([]byte(u))
+		ph := sha256.Sum256([]byte(p))
+		userOK := subtle.ConstantTimeCompare(uh[:], userHash[:])
+		passwordOK := subtle.ConstantTimeCompare(ph[:], passwordHash[:])
+		return userOK&passwordOK == 1
+	}
+}
@@ -0,0 +1,90 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+type user struct {
+	Name   string
+	Scopes []string
+}
+
+func TestRequireScope(t *testing.T) {
+	extract := func(principal any) []string {
+		return principal.(user).Scopes
+	}
+
+	h := httph.RequireScope(extract, "posts:read", "posts:write")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(principal any) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if principal != nil {
+			req = req.WithContext(httph.WithPrincipal(req.Context(), principal))
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("allows a principal with all scopes", func(t *testing.T) {
+		res := request(user{Name: "me", Scopes: []string{"posts:write", "posts:read", "admin"}})
+		is.Equal(t, http.StatusOK, res.Code)
+	})
+
+	t.Run("returns forbidden for a principal missing a scope", func(t *testing.T) {
+		res := request(user{Name: "me", Scopes: []string{"posts:read"}})
+		is.Equal(t, http.StatusForbidden, res.Code)
+		is.Equal(t, "missing scope posts:write", readBody(t, res))
+	})
+
+	t.Run("returns unauthorized without a principal", func(t *testing.T) {
+		res := request(nil)
+		is.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+}
+
+func TestBasicAuth(t *testing.T) {
+	h := httph.BasicAuth(`Internal "tools"`, httph.StaticCredentials("me", "secret"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("Hello " + httph.PrincipalFromContext(r.Context()).(string)))
+		}))
+
+	request := func(user, password string, set bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if set {
+			req.SetBasicAuth(user, password)
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("passes valid credentials through with the user as principal", func(t *testing.T) {
+		res := request("me", "secret", true)
+
+		is.Equal(t, http.StatusOK, res.Code)
+		is.Equal(t, "Hello me", res.Body.String())
+	})
+
+	t.Run("returns unauthorized with a challenge for invalid credentials", func(t *testing.T) {
+		for _, creds := range [][2]string{{"me", "wrong"}, {"you", "secret"}, {"", ""}} {
+			res := request(creds[0], creds[1], true)
+
+			is.Equal(t, http.StatusUnauthorized, res.Code)
+			is.Equal(t, `Basic realm="Internal \"tools\"", charset="UTF-8"`, res.Header().Get("WWW-Authenticate"))
+		}
+	})
+
+	t.Run("returns unauthorized with a challenge for a missing header", func(t *testing.T) {
+		res := request("", "", false)
+
+		is.Equal(t, http.StatusUnauthorized, res.Code)
+		is.Equal(t, `Basic realm="Internal \"tools\"", charset="UTF-8"`, res.Header().Get("WWW-Authenticate"))
+	})
+}
@@ -0,0 +1,54 @@
+package httph
+
+import (
+	"net/http"
+)
+
+// BatchResult is a response for batch operations in JSONHandler, with a result for each item.
+// Add item results in the same order as the items in the request.
+//
+// The response status depends on the item results, through the statusCodeGiver interface:
+//   - If there are no items, or all items succeeded, the status is http.StatusOK.
+//   - If all items share the same status, for example http.StatusBadRequest, that status is used.
+//   - Otherwise, when some items succeeded and others failed, the status is http.StatusMultiStatus,
+//     and clients must look at each item's status.
+type BatchResult[T any] struct {
+	Items []BatchItemResult[T]
+}
+
+// BatchItemResult is the result for a single item in a BatchResult.
+// Result is set on success, and Error is set on failure.
+type BatchItemResult[T any] struct {
+	Status int
+	Result *T     `json:",omitempty"`
+	Error  string `json:",omitempty"`
+}
+
+// Add the result for the next item.
+// If err is nil, the item status is http.StatusOK and the result is v.
+// Otherwise, the item status and error message are chosen like for errors in JSONHandler, so the status is that of
+// an error that satisfies statusCodeGiver, or a wrapped HTTPError or ValidationError,
+// and defaults to http.StatusInternalServerError. v is ignored.
+func (b *BatchResult[T]) Add(v T, err error) {
+	if err == nil {
+		b.Items = append(b.Items, BatchItemResult[T]{Status: http.StatusOK, Result: &v})
+		return
+	}
+
+	b.Items = append(b.Items, BatchItemResult[T]{Status: errorStatusCode(err), Error: errorMessage(err)})
+}
+
+// StatusCode satisfies statusCodeGiver.
+func (b BatchResult[T]) StatusCode() int {
+	if len(b.Items) == 0 {
+		return http.StatusOK
+	}
+
+	code := b.Items[0].Status
+	for _, item := range b.Items[1:] {
+		if item.Status != code {
+			return http.StatusMultiStatus
+		}
+	}
+	return code
+}
@@ -0,0 +1,80 @@
+package httph_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestBatchResult(t *testing.T) {
+	type item struct {
+		Name string
+	}
+
+	newHandler := func(names ...string) http.Handler {
+		return httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (httph.BatchResult[item], error) {
+			var res httph.BatchResult[item]
+			for _, name := range names {
+				if name == "" {
+					res.Add(item{}, &httpError{http.StatusBadRequest})
+					continue
+				}
+				res.Add(item{Name: name}, nil)
+			}
+			return res, nil
+		})
+	}
+
+	t.Run("returns multi-status with per-item results on partial success", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+		res := httptest.NewRecorder()
+
+		newHandler("a", "").ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusMultiStatus, res.Result().StatusCode)
+		is.Equal(t, `{"Items":[{"Status":200,"Result":{"Name":"a"}},{"Status":400,"Error":"Bad Request"}]}`, readBody(t, res))
+	})
+
+	t.Run("returns OK when all items succeed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+		res := httptest.NewRecorder()
+
+		newHandler("a", "b").ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+	})
+
+	t.Run("returns the shared status when all items fail the same way", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+		res := httptest.NewRecorder()
+
+		newHandler("", "").ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+	})
+
+	t.Run("returns OK for no items", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+		res := httptest.NewRecorder()
+
+		newHandler().ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, `{"Items":null}`, readBody(t, res))
+	})
+
+	t.Run("uses the status code and message of wrapped errors", func(t *testing.T) {
+		var b httph.BatchResult[item]
+		b.Add(item{}, fmt.Errorf("adding: %w", httph.NewHTTPError(http.StatusConflict, errors.New("secret"))))
+
+		is.Equal(t, http.StatusConflict, b.Items[0].Status)
+		is.Equal(t, "Conflict", b.Items[0].Error)
+	})
+}
@@ -0,0 +1,192 @@
+package httph
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrBodyReadTimeout is returned from reading the request body when the client sends it too slowly.
+var ErrBodyReadTimeout = errors.New("request body read timeout")
+
+// BodyReadTimeoutOptions for the BodyReadTimeout Middleware.
+// At least one of Timeout and MinBytesPerSecond must be set.
+type BodyReadTimeoutOptions struct {
+	// Timeout is the absolute time allowed for reading the whole request body, counted from when the middleware runs.
+	Timeout time.Duration
+
+	// MinBytesPerSecond is the minimum throughput required while reading the request body.
+	// After Grace has passed, at least MinBytesPerSecond * (elapsed - Grace) bytes must have been read at any time.
+	MinBytesPerSecond int64
+
+	// Grace is the time before MinBytesPerSecond is enforced. Defaults to one second.
+	Grace time.Duration
+}
+
+// BodyReadTimeout is Middleware to protect against clients sending the request body too slowly,
+// also known as slow-body or Slowloris-style attacks.
+// It wraps the request body and aborts with http.StatusRequestTimeout if the body is not read within
+// the absolute Timeout, or if throughput falls below MinBytesPerSecond.
+// Where supported, the underlying connection read deadline is also set through http.ResponseController,
+// so a client that stalls completely is also caught.
+// Errors from body readers already in place, like http.MaxBytesReader, are returned unchanged,
+// and readers wrapped around the body later, like in JSONHandler, see ErrBodyReadTimeout on timeout.
+func BodyReadTimeout(opts BodyReadTimeoutOptions) Middleware {
+	if opts.Timeout < 0 || opts.MinBytesPerSecond < 0 || opts.Grace < 0 {
+		panic("invalid body read timeout options")
+	}
+	if opts.Timeout == 0 && opts.MinBytesPerSecond == 0 {
+		panic("no timeout or minimum throughput")
+	}
+	if opts.Grace == 0 {
+		opts.Grace = time.Second
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			tw := &bodyReadTimeoutWriter{ResponseWriter: w}
+			br := &bodyReadTimeoutReader{
+				body:  r.Body,
+				opts:  opts,
+				start: time.Now(),
+				rc:    http.NewResponseController(w),
+				w:     tw,
+			}
+			defer br.clearDeadline()
+
+			r.Body = br
+			next.ServeHTTP(tw, r)
+		})
+	}
+}
+
+type bodyReadTimeoutReader struct {
+	body  io.ReadCloser
+	opts  BodyReadTimeoutOptions
+	start time.Time
+	read  int64
+	rc    *http.ResponseController
+	w     *bodyReadTimeoutWriter
+	done  bool
+}
+
+func (b *bodyReadTimeoutReader) Read(p []byte) (int, error) {
+	if b.done {
+		return 0, ErrBodyReadTimeout
+	}
+
+	deadline := b.deadline()
+	// Ignore errors here, the deadline is also checked after reading, which works for all connection types
+	_ = b.rc.SetReadDeadline(deadline)
+
+	n, err := b.body.Read(p)
+	b.read += int64(n)
+
+	if time.Now().After(deadline) || errors.Is(err, os.ErrDeadlineExceeded) {
+		b.done = true
+		b.w.timeout()
+		return n, ErrBodyReadTimeout
+	}
+
+	if err == io.EOF {
+		b.clearDeadline()
+	}
+
+	return n, err
+}
+
+func (b *bodyReadTimeoutReader) Close() error {
+	return b.body.Close()
+}
+
+// deadline for the next read to complete, given the bytes read so far.
+func (b *bodyReadTimeoutReader) deadline() time.Time {
+	var deadline time.Time
+	if b.opts.Timeout > 0 {
+		deadline = b.start.Add(b.opts.Timeout)
+	}
+
+	if b.opts.MinBytesPerSecond > 0 {
+		allowed := b.opts.Grace + time.Duration(float64(b.read)/float64(b.opts.MinBytesPerSecond)*float64(time.Second))
+		throughputDeadline := b.start.Add(allowed)
+		if deadline.IsZero() || throughputDeadline.Before(deadline) {
+			deadline = throughputDeadline
+		}
+	}
+
+	return deadline
+}
+
+func (b *bodyReadTimeoutReader) clearDeadline() {
+	_ = b.rc.SetReadDeadline(time.Time{})
+}
+
+// bodyReadTimeoutWriter writes the timeout response and discards anything the handler writes afterwards.
+type bodyReadTimeoutWriter struct {
+	http.ResponseWriter
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (w *bodyReadTimeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timedOut || w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *bodyReadTimeoutWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timedOut {
+		return 0, ErrBodyReadTimeout
+	}
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(p)
+}
+
+// Unwrap the http.ResponseWriter for http.ResponseController.
+func (w *bodyReadTimeoutWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// timeout writes the timeout response, if nothing has been written yet.
+func (w *bodyReadTimeoutWriter) timeout() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.wroteHeader || w.timedOut {
+		return
+	}
+	w.timedOut = true
+	w.ResponseWriter.Header().Set("Connection", "close")
+	http.Error(w.ResponseWriter, ErrBodyReadTimeout.Error(), http.StatusRequestTimeout)
+}
+
+// Flush the underlying http.ResponseWriter, if it supports it.
+func (w *bodyReadTimeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timedOut {
+		return
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		f.Flush()
+	}
+}
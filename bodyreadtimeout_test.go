@@ -0,0 +1,130 @@
+package httph_test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+// slowReader returns one byte per read, sleeping before each.
+type slowReader struct {
+	r     io.Reader
+	sleep time.Duration
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(s.sleep)
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return s.r.Read(p)
+}
+
+func TestBodyReadTimeout(t *testing.T) {
+	t.Run("passes through a body that is read fast enough", func(t *testing.T) {
+		h := httph.BodyReadTimeout(httph.BodyReadTimeoutOptions{Timeout: time.Second, MinBytesPerSecond: 1})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				is.NotError(t, err)
+				_, _ = w.Write(body)
+			}))
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("hello"))
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "hello", readBody(t, res))
+	})
+
+	t.Run("returns request timeout if the body is not read within the timeout", func(t *testing.T) {
+		var readErr error
+		h := httph.BodyReadTimeout(httph.BodyReadTimeoutOptions{Timeout: 10 * time.Millisecond})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, readErr = io.ReadAll(r.Body)
+				http.Error(w, "should be discarded", http.StatusBadRequest)
+			}))
+
+		req := httptest.NewRequest(http.MethodPost, "/", &slowReader{r: strings.NewReader("hello"), sleep: 5 * time.Millisecond})
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.True(t, errors.Is(readErr, httph.ErrBodyReadTimeout))
+		is.Equal(t, http.StatusRequestTimeout, res.Result().StatusCode)
+		is.Equal(t, "request body read timeout", readBody(t, res))
+	})
+
+	t.Run("returns request timeout if throughput is too low", func(t *testing.T) {
+		h := httph.BodyReadTimeout(httph.BodyReadTimeoutOptions{MinBytesPerSecond: 1000, Grace: time.Millisecond})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.ReadAll(r.Body)
+			}))
+
+		req := httptest.NewRequest(http.MethodPost, "/", &slowReader{r: strings.NewReader("hello"), sleep: 5 * time.Millisecond})
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusRequestTimeout, res.Result().StatusCode)
+	})
+
+	t.Run("does not replace errors from a max bytes reader", func(t *testing.T) {
+		var readErr error
+		h := httph.BodyReadTimeout(httph.BodyReadTimeoutOptions{Timeout: time.Second})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, readErr = io.ReadAll(http.MaxBytesReader(w, r.Body, 2))
+			}))
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("hello"))
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		var maxBytesErr *http.MaxBytesError
+		is.True(t, errors.As(readErr, &maxBytesErr))
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+	})
+
+	t.Run("returns request timeout for a client that stalls on a real connection", func(t *testing.T) {
+		h := httph.BodyReadTimeout(httph.BodyReadTimeoutOptions{Timeout: 50 * time.Millisecond})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.ReadAll(r.Body)
+			}))
+
+		s := httptest.NewServer(h)
+		defer s.Close()
+
+		conn, err := net.Dial("tcp", s.Listener.Addr().String())
+		is.NotError(t, err)
+		defer func() {
+			_ = conn.Close()
+		}()
+
+		_, err = conn.Write([]byte("POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 10\r\n\r\nh"))
+		is.NotError(t, err)
+
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		is.NotError(t, err)
+		is.Equal(t, http.StatusRequestTimeout, res.StatusCode)
+	})
+
+	t.Run("panics without timeout or minimum throughput", func(t *testing.T) {
+		defer func() {
+			is.True(t, recover() != nil)
+		}()
+		httph.BodyReadTimeout(httph.BodyReadTimeoutOptions{})
+	})
+}
@@ -0,0 +1,242 @@
+package httph
+
+import (
+	"bytes"
+	"container/list"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheOptions for the Cache Middleware.
+type CacheOptions struct {
+	// TTL of cached responses. Required.
+	TTL time.Duration
+
+	// MaxEntries in the cache, after which the least recently used entry is evicted. Defaults to 1000.
+	MaxEntries int
+
+	// MaxEntrySizeBytes of a response body to cache. Larger responses are not cached. Defaults to 1 MiB.
+	MaxEntrySizeBytes int
+
+	// Now is the clock used. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Cache is Middleware to cache responses to GET requests in memory, keyed by the request host and URL,
+// and serve them from memory until the TTL has passed, with an Age header in seconds.
+// Only 2xx responses are cached, with their status code, headers, and body, and not if their Cache-Control
+// has no-store or private, or the handler sets a Vary header, because responses aren't cached per request header,
+// or they have a Set-Cookie header, because cookies are per client.
+// A Vary header already set when Cache is called, like by Compress before it, is ignored, and kept on cached responses.
+// Requests with an Authorization header or a Cache-Control no-store directive are passed on to the next handler,
+// and their responses aren't cached.
+// Put Cache after Middleware that varies responses by request header, like Compress, so it caches what's before that.
+// Panics if the TTL isn't positive.
+func Cache(opts CacheOptions) Middleware {
+	if opts.TTL <= 0 {
+		panic("invalid cache TTL")
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 1000
+	}
+	if opts.MaxEntrySizeBytes <= 0 {
+		opts.MaxEntrySizeBytes = 1 << 20
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	c := &responseCache{
+		maxEntries: opts.MaxEntries,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet || r.Header.Get("Authorization") != "" ||
+				hasCacheDirective(r.Header, "no-store") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := r.Host + r.URL.RequestURI()
+			now := opts.Now()
+
+			if e, ok := c.get(key, now); ok {
+				for name, values := range e.header {
+					w.Header()[name] = values
+				}
+				w.Header().Set("Age", strconv.FormatInt(int64(now.Sub(e.stored).Seconds()), 10))
+				w.WriteHeader(e.code)
+				// There's not much we can do about an error here, so ignore it
+				_, _ = w.Write(e.body)
+				return
+			}
+
+			cw := &cacheWriter{
+				ResponseWriter: w,
+				maxSize:        opts.MaxEntrySizeBytes,
+				outerVary:      slices.Clone(w.Header().Values("Vary")),
+			}
+			next.ServeHTTP(cw, r)
+
+			if cw.code == 0 {
+				cw.code = http.StatusOK
+			}
+			if !cw.cacheable() {
+				return
+			}
+			c.set(key, &cacheEntry{
+				code:    cw.code,
+				header:  cw.header,
+				body:    cw.buf.Bytes(),
+				stored:  now,
+				expires: now.Add(opts.TTL),
+			})
+		})
+	}
+}
+
+// hasCacheDirective returns whether the Cache-Control header has the directive, with or without a value.
+func hasCacheDirective(h http.Header, directive string) bool {
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			name, _, _ := strings.Cut(strings.TrimSpace(d), "=")
+			if strings.EqualFold(name, directive) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type cacheEntry struct {
+	key     string
+	code    int
+	header  http.Header
+	body    []byte
+	stored  time.Time
+	expires time.Time
+}
+
+// responseCache is an LRU cache of responses.
+type responseCache struct {
+	lock       sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	lru        *list.List
+}
+
+// get the entry for key, if it's there and hasn't expired.
+func (c *responseCache) get(key string, now time.Time) (*cacheEntry, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if !now.Before(e.expires) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return e, true
+}
+
+// set the entry for key, evicting the least recently used entry if the cache is full.
+func (c *responseCache) set(key string, e *cacheEntry) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e.key = key
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	if c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// cacheWriter passes the response on, and keeps a copy of the status code, headers, and body to cache.
+type cacheWriter struct {
+	http.ResponseWriter
+	maxSize   int
+	outerVary []string
+	code      int
+	header    http.Header
+	buf       bytes.Buffer
+	tooLarge  bool
+}
+
+func (w *cacheWriter) WriteHeader(code int) {
+	// Informational responses aren't the final response
+	if w.code == 0 && code >= 200 {
+		w.code = code
+		w.header = w.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cacheWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(p)
+	if !w.tooLarge {
+		if w.buf.Len()+n > w.maxSize {
+			w.tooLarge = true
+			w.buf = bytes.Buffer{}
+		} else {
+			w.buf.Write(p[:n])
+		}
+	}
+	return n, err
+}
+
+// Flush satisfies http.Flusher.
+func (w *cacheWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError is like Flush but returns an error, and is used by http.ResponseController.
+func (w *cacheWriter) FlushError() error {
+	if w.code == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap the http.ResponseWriter for http.ResponseController.
+func (w *cacheWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// cacheable returns whether the response can be cached.
+// The Vary header from outer Middleware is removed from the header to cache, because it's still set on cache hits.
+func (w *cacheWriter) cacheable() bool {
+	if w.tooLarge || w.code < 200 || w.code > 299 {
+		return false
+	}
+	if w.header == nil {
+		w.header = w.Header().Clone()
+	}
+	if !slices.Equal(w.header.Values("Vary"), w.outerVary) {
+		return false
+	}
+	w.header.Del("Vary")
+	return !hasCacheDirective(w.header, "no-store") && !hasCacheDirective(w.header, "private") &&
+		w.header.Get("Set-Cookie") == ""
+}
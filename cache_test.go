@@ -0,0 +1,161 @@
+package httph_test
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestCache(t *testing.T) {
+	setup := func(opts httph.CacheOptions, h http.HandlerFunc) (http.Handler, *time.Time, *int) {
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		opts.Now = func() time.Time {
+			return now
+		}
+		var calls int
+		return httph.Cache(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			h(w, r)
+		})), &now, &calls
+	}
+
+	request := func(h http.Handler, method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res
+	}
+
+	created := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, `{"path":%q}`, r.URL.Path)
+	}
+
+	t.Run("serves a cached response within the TTL with an age header", func(t *testing.T) {
+		h, now, calls := setup(httph.CacheOptions{TTL: time.Minute}, created)
+
+		res := request(h, http.MethodGet, "/a")
+		is.Equal(t, http.StatusCreated, res.Code)
+		is.Equal(t, "", res.Header().Get("Age"))
+
+		*now = now.Add(30 * time.Second)
+		res = request(h, http.MethodGet, "/a")
+		is.Equal(t, http.StatusCreated, res.Code)
+		is.Equal(t, "application/json", res.Header().Get("Content-Type"))
+		is.Equal(t, "30", res.Header().Get("Age"))
+		is.Equal(t, `{"path":"/a"}`, res.Body.String())
+		is.Equal(t, 1, *calls)
+
+		res = request(h, http.MethodGet, "/b")
+		is.Equal(t, `{"path":"/b"}`, res.Body.String())
+		is.Equal(t, 2, *calls)
+	})
+
+	t.Run("calls the next handler again after the TTL", func(t *testing.T) {
+		h, now, calls := setup(httph.CacheOptions{TTL: time.Minute}, created)
+
+		request(h, http.MethodGet, "/")
+		*now = now.Add(time.Minute)
+		res := request(h, http.MethodGet, "/")
+
+		is.Equal(t, "", res.Header().Get("Age"))
+		is.Equal(t, 2, *calls)
+	})
+
+	t.Run("evicts the least recently used entry", func(t *testing.T) {
+		h, _, calls := setup(httph.CacheOptions{TTL: time.Minute, MaxEntries: 2}, created)
+
+		request(h, http.MethodGet, "/a")
+		request(h, http.MethodGet, "/b")
+		request(h, http.MethodGet, "/a")
+		request(h, http.MethodGet, "/c")
+		is.Equal(t, 3, *calls)
+
+		request(h, http.MethodGet, "/a")
+		is.Equal(t, 3, *calls)
+		request(h, http.MethodGet, "/b")
+		is.Equal(t, 4, *calls)
+	})
+
+	t.Run("does not cache non-GET requests, errors, no-store, or too large responses", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			opts   httph.CacheOptions
+			method string
+			h      http.HandlerFunc
+		}{
+			{name: "post", method: http.MethodPost, h: created},
+			{name: "error", method: http.MethodGet, h: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "oh no", http.StatusInternalServerError)
+			}},
+			{name: "no-store", method: http.MethodGet, h: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Cache-Control", "no-store")
+				_, _ = w.Write([]byte("Yo"))
+			}},
+			{name: "vary", method: http.MethodGet, h: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Vary", "Accept-Encoding")
+				_, _ = w.Write([]byte("Yo"))
+			}},
+			{name: "too large", opts: httph.CacheOptions{MaxEntrySizeBytes: 1}, method: http.MethodGet, h: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("Yo"))
+			}},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				test.opts.TTL = time.Minute
+				h, _, calls := setup(test.opts, test.h)
+
+				request(h, test.method, "/")
+				request(h, test.method, "/")
+				is.Equal(t, 2, *calls)
+			})
+		}
+	})
+
+	t.Run("caches behind Compress, which sets a Vary header before it", func(t *testing.T) {
+		body := strings.Repeat("Yo", 1024)
+		h, _, calls := setup(httph.CacheOptions{TTL: time.Minute}, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(body))
+		})
+		h = httph.Compress(httph.CompressOptions{})(h)
+
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+
+			is.Equal(t, "gzip", res.Header().Get("Content-Encoding"))
+			is.Equal(t, "Accept-Encoding", strings.Join(res.Header().Values("Vary"), ", "))
+			gr, err := gzip.NewReader(res.Body)
+			is.NotError(t, err)
+			b, err := io.ReadAll(gr)
+			is.NotError(t, err)
+			is.Equal(t, body, string(b))
+		}
+
+		res := request(h, http.MethodGet, "/")
+		is.Equal(t, "", res.Header().Get("Content-Encoding"))
+		is.Equal(t, body, res.Body.String())
+		is.Equal(t, 1, *calls)
+	})
+
+	t.Run("panics without a TTL", func(t *testing.T) {
+		defer func() {
+			is.Equal(t, any("invalid cache TTL"), recover())
+		}()
+		httph.Cache(httph.CacheOptions{})
+	})
+}
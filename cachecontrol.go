@@ -0,0 +1,75 @@
+package httph
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CacheControlOptions for the CacheControl Middleware.
+type CacheControlOptions struct {
+	// MaxAge for caching the response. Zero omits the directive.
+	MaxAge time.Duration
+
+	// Public allows shared caches, like CDNs, to store the response.
+	Public bool
+
+	// Private only allows the client's own cache to store the response.
+	Private bool
+
+	// NoStore disallows storing the response in any cache. Can't be combined with the other options.
+	NoStore bool
+
+	// NoCache requires caches to revalidate the response before using it.
+	NoCache bool
+
+	// Immutable tells clients the response never changes while fresh, so they don't revalidate it.
+	Immutable bool
+}
+
+// CacheControl is Middleware to set the Cache-Control header from the options, like "public, max-age=3600".
+// The header is set before calling the next handler, so handlers can override it.
+// Panics on conflicting options, like Public with Private, or NoStore with any other option.
+// See https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Cache-Control
+func CacheControl(opts CacheControlOptions) Middleware {
+	if opts.Public && opts.Private {
+		panic("public and private cache control are exclusive")
+	}
+	if opts.NoStore && (opts.MaxAge != 0 || opts.Public || opts.Private || opts.NoCache || opts.Immutable) {
+		panic("no-store cache control can't be combined with other directives")
+	}
+	if opts.MaxAge < 0 {
+		panic("invalid max age")
+	}
+
+	var directives []string
+	switch {
+	case opts.Public:
+		directives = append(directives, "public")
+	case opts.Private:
+		directives = append(directives, "private")
+	}
+	if opts.NoStore {
+		directives = append(directives, "no-store")
+	}
+	if opts.NoCache {
+		directives = append(directives, "no-cache")
+	}
+	if opts.MaxAge > 0 {
+		directives = append(directives, fmt.Sprintf("max-age=%d", int64(opts.MaxAge.Seconds())))
+	}
+	if opts.Immutable {
+		directives = append(directives, "immutable")
+	}
+	value := strings.Join(directives, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if value != "" {
+				w.Header().Set("Cache-Control", value)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestCacheControl(t *testing.T) {
+	t.Run("renders the options into the header", func(t *testing.T) {
+		for _, test := range []struct {
+			opts     httph.CacheControlOptions
+			expected string
+		}{
+			{httph.CacheControlOptions{Public: true, MaxAge: time.Hour}, "public, max-age=3600"},
+			{httph.CacheControlOptions{Public: true, MaxAge: 365 * 24 * time.Hour, Immutable: true}, "public, max-age=31536000, immutable"},
+			{httph.CacheControlOptions{Private: true, NoCache: true}, "private, no-cache"},
+			{httph.CacheControlOptions{NoStore: true}, "no-store"},
+			{httph.CacheControlOptions{}, ""},
+		} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			res := httptest.NewRecorder()
+
+			h := httph.CacheControl(test.opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			h.ServeHTTP(res, req)
+
+			is.Equal(t, test.expected, res.Result().Header.Get("Cache-Control"))
+		}
+	})
+
+	t.Run("can be overridden by the next handler", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h := httph.CacheControl(httph.CacheControlOptions{Public: true, MaxAge: time.Hour})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Cache-Control", "no-store")
+			}))
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, "no-store", res.Result().Header.Get("Cache-Control"))
+	})
+
+	t.Run("panics on no-store with other options", func(t *testing.T) {
+		for _, opts := range []httph.CacheControlOptions{
+			{NoStore: true, MaxAge: time.Hour},
+			{NoStore: true, Public: true},
+			{NoStore: true, NoCache: true},
+		} {
+			func() {
+				defer func() {
+					is.Equal(t, any("no-store cache control can't be combined with other directives"), recover())
+				}()
+				httph.CacheControl(opts)
+			}()
+		}
+	})
+
+	t.Run("panics on public with private", func(t *testing.T) {
+		defer func() {
+			is.True(t, recover() != nil)
+		}()
+		httph.CacheControl(httph.CacheControlOptions{Public: true, Private: true})
+	})
+}
@@ -0,0 +1,195 @@
+package httph
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// CompressOptions for the Compress Middleware.
+type CompressOptions struct {
+	// Level of compression, from gzip.BestSpeed to gzip.BestCompression. Defaults to gzip.DefaultCompression.
+	Level int
+
+	// MinSizeBytes of the response body to compress. Smaller responses are sent as is. Defaults to 1024.
+	MinSizeBytes int
+
+	// Deflate enables the deflate encoding for clients that don't accept gzip. Disabled by default.
+	Deflate bool
+}
+
+// Compress is Middleware to compress response bodies with gzip, or deflate if enabled in the options,
+// depending on the request Accept-Encoding header. The Content-Encoding and Vary headers are set accordingly.
+// Responses smaller than MinSizeBytes, responses that already have a Content-Encoding,
+// and content types that are already compressed, like most images, video, and audio, are sent as is.
+// Flushing the response writer flushes the compressed data, so Compress works with streaming handlers.
+func Compress(opts CompressOptions) Middleware {
+	if opts.Level == 0 {
+		opts.Level = gzip.DefaultCompression
+	}
+	if opts.Level < gzip.HuffmanOnly || opts.Level > gzip.BestCompression {
+		panic("invalid compression level")
+	}
+	if opts.MinSizeBytes <= 0 {
+		opts.MinSizeBytes = 1024
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			var encoding string
+			switch {
+			case acceptsEncoding(r, "gzip"):
+				encoding = "gzip"
+			case opts.Deflate && acceptsEncoding(r, "deflate"):
+				encoding = "deflate"
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, opts: opts}
+			next.ServeHTTP(cw, r)
+			cw.finish()
+		})
+	}
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	opts     CompressOptions
+	buf      bytes.Buffer
+	code     int
+	started  bool
+	cw       interface {
+		io.WriteCloser
+		Flush() error
+	}
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	// Informational responses are sent right away
+	if w.started || (code >= 100 && code < 200) {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.started {
+		if w.cw != nil {
+			return w.cw.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+
+	n, _ := w.buf.Write(p)
+	if w.buf.Len() >= w.opts.MinSizeBytes {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// Flush the compressed data written so far.
+func (w *compressWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError is like Flush but returns an error, and is used by http.ResponseController.
+func (w *compressWriter) FlushError() error {
+	// A flushed response is streamed, so it's compressed even if the body so far is small
+	if err := w.start(true); err != nil {
+		return err
+	}
+	if w.cw != nil {
+		if err := w.cw.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap the http.ResponseWriter for http.ResponseController.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// start the response, compressed if sized is true and the response can be compressed,
+// and write anything buffered.
+func (w *compressWriter) start(sized bool) error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+
+	// Set the content type from the uncompressed body, because net/http would sniff the compressed one
+	if _, ok := w.Header()["Content-Type"]; !ok && w.buf.Len() > 0 {
+		w.Header().Set("Content-Type", http.DetectContentType(w.buf.Bytes()))
+	}
+
+	if sized && w.code != http.StatusNoContent && w.code != http.StatusNotModified &&
+		w.Header().Get("Content-Encoding") == "" && compressible(w.Header().Get("Content-Type")) {
+		w.Header().Set("Content-Encoding", w.encoding)
+		w.Header().Del("Content-Length")
+		switch w.encoding {
+		case "gzip":
+			w.cw, _ = gzip.NewWriterLevel(w.ResponseWriter, w.opts.Level)
+		case "deflate":
+			w.cw, _ = flate.NewWriter(w.ResponseWriter, w.opts.Level)
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.code)
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.cw != nil {
+		_, err = w.cw.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+// finish the response, and close the compressor.
+func (w *compressWriter) finish() {
+	// There's not much we can do about errors here, so ignore them
+	_ = w.start(w.buf.Len() >= w.opts.MinSizeBytes)
+	if w.cw != nil {
+		_ = w.cw.Close()
+	}
+}
+
+// compressible returns whether the content type is worth compressing.
+// Most image, video, and audio formats, and archives, are already compressed.
+func compressible(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "image/svg+xml", mediaType == "image/bmp":
+		return true
+	case strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "audio/"):
+		return false
+	}
+	switch mediaType {
+	case "application/gzip", "application/x-gzip", "application/zip", "application/zstd", "application/x-bzip2",
+		"application/x-7z-compressed", "application/x-rar-compressed", "font/woff", "font/woff2":
+		return false
+	}
+	return true
+}
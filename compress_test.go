@@ -0,0 +1,151 @@
+package httph_test
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat(`{"message":"Yo"}`, 100)
+
+	handler := func(contentType, body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if contentType != "" {
+				w.Header().Set("Content-Type", contentType)
+			}
+			_, _ = w.Write([]byte(body))
+		})
+	}
+
+	request := func(h http.Handler, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("compresses the body with gzip", func(t *testing.T) {
+		h := httph.Compress(httph.CompressOptions{})(handler("application/json", body))
+		res := request(h, "br, gzip")
+
+		is.Equal(t, http.StatusOK, res.Code)
+		is.Equal(t, "gzip", res.Header().Get("Content-Encoding"))
+		is.Equal(t, "Accept-Encoding", res.Header().Get("Vary"))
+		is.Equal(t, "application/json", res.Header().Get("Content-Type"))
+
+		gr, err := gzip.NewReader(res.Body)
+		is.NotError(t, err)
+		decompressed, err := io.ReadAll(gr)
+		is.NotError(t, err)
+		is.Equal(t, body, string(decompressed))
+	})
+
+	t.Run("compresses the body with deflate if enabled", func(t *testing.T) {
+		h := httph.Compress(httph.CompressOptions{Deflate: true})(handler("application/json", body))
+		res := request(h, "deflate")
+
+		is.Equal(t, "deflate", res.Header().Get("Content-Encoding"))
+
+		decompressed, err := io.ReadAll(flate.NewReader(res.Body))
+		is.NotError(t, err)
+		is.Equal(t, body, string(decompressed))
+	})
+
+	t.Run("does not compress for unsupported encodings", func(t *testing.T) {
+		h := httph.Compress(httph.CompressOptions{})(handler("application/json", body))
+
+		for _, acceptEncoding := range []string{"", "br", "deflate", "gzip;q=0"} {
+			res := request(h, acceptEncoding)
+
+			is.Equal(t, "", res.Header().Get("Content-Encoding"))
+			is.Equal(t, "Accept-Encoding", res.Header().Get("Vary"))
+			is.Equal(t, body, res.Body.String())
+		}
+	})
+
+	t.Run("does not compress bodies smaller than the minimum size", func(t *testing.T) {
+		h := httph.Compress(httph.CompressOptions{})(handler("application/json", `{"message":"Yo"}`))
+		res := request(h, "gzip")
+
+		is.Equal(t, "", res.Header().Get("Content-Encoding"))
+		is.Equal(t, `{"message":"Yo"}`, res.Body.String())
+	})
+
+	t.Run("does not compress already compressed content types", func(t *testing.T) {
+		for _, contentType := range []string{"image/png", "video/mp4", "application/zip"} {
+			h := httph.Compress(httph.CompressOptions{})(handler(contentType, body))
+			res := request(h, "gzip")
+
+			is.Equal(t, "", res.Header().Get("Content-Encoding"))
+			is.Equal(t, body, res.Body.String())
+		}
+	})
+
+	t.Run("does not compress responses with a content encoding", func(t *testing.T) {
+		h := httph.Compress(httph.CompressOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", "br")
+			_, _ = w.Write([]byte(body))
+		}))
+		res := request(h, "gzip, br")
+
+		is.Equal(t, "br", res.Header().Get("Content-Encoding"))
+		is.Equal(t, body, res.Body.String())
+	})
+
+	t.Run("sets the content type from the uncompressed body", func(t *testing.T) {
+		h := httph.Compress(httph.CompressOptions{})(handler("", "<!doctype html>"+body))
+		res := request(h, "gzip")
+
+		is.Equal(t, "gzip", res.Header().Get("Content-Encoding"))
+		is.Equal(t, "text/html; charset=utf-8", res.Header().Get("Content-Type"))
+	})
+
+	t.Run("keeps the status code", func(t *testing.T) {
+		h := httph.Compress(httph.CompressOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(body))
+		}))
+		res := request(h, "gzip")
+
+		is.Equal(t, http.StatusCreated, res.Code)
+		is.Equal(t, "gzip", res.Header().Get("Content-Encoding"))
+	})
+
+	t.Run("flushes compressed data for streaming handlers", func(t *testing.T) {
+		h := httph.Compress(httph.CompressOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: 1\n\n"))
+			w.(http.Flusher).Flush()
+			_, _ = w.Write([]byte("data: 2\n\n"))
+		}))
+		res := request(h, "gzip")
+
+		is.True(t, res.Flushed)
+		is.Equal(t, "gzip", res.Header().Get("Content-Encoding"))
+
+		gr, err := gzip.NewReader(res.Body)
+		is.NotError(t, err)
+		decompressed, err := io.ReadAll(gr)
+		is.NotError(t, err)
+		is.Equal(t, "data: 1\n\ndata: 2\n\n", string(decompressed))
+	})
+
+	t.Run("panics on invalid compression level", func(t *testing.T) {
+		defer func() {
+			is.True(t, recover() != nil)
+		}()
+		httph.Compress(httph.CompressOptions{Level: 10})
+	})
+}
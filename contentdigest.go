@@ -0,0 +1,53 @@
+package httph
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+)
+
+// ContentDigestOptions for the ContentDigest Middleware.
+type ContentDigestOptions struct {
+	// MaxSizeBytes of the response body to buffer for the digest. Defaults to 1 MiB.
+	MaxSizeBytes int64
+}
+
+// ContentDigest is Middleware to set a Content-Digest header with the SHA-256 digest of the response body,
+// so clients can verify the body they received.
+// The header value is a structured field dictionary with a byte sequence, like "sha-256=:<base64>:".
+// Because the digest must be sent before the body, the response is buffered up to MaxSizeBytes.
+// Responses larger than that, or that are flushed by the handler, are streamed without the header.
+// HEAD requests, responses without a body, and responses that already have a Content-Digest header are left alone.
+// See https://www.rfc-editor.org/rfc/rfc9530
+func ContentDigest(opts ContentDigestOptions) Middleware {
+	if opts.MaxSizeBytes <= 0 {
+		opts.MaxSizeBytes = 1 << 20
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			bw := &bufferedResponseWriter{ResponseWriter: w, max: opts.MaxSizeBytes}
+			next.ServeHTTP(bw, r)
+			finishContentDigest(bw)
+		})
+	}
+}
+
+// finishContentDigest sets the digest header and writes the buffered response.
+func finishContentDigest(w *bufferedResponseWriter) {
+	if !w.buffered() {
+		return
+	}
+
+	if w.code != http.StatusNoContent && w.code != http.StatusNotModified && w.Header().Get("Content-Digest") == "" {
+		digest := sha256.Sum256(w.buf.Bytes())
+		w.Header().Set("Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(digest[:])+":")
+	}
+
+	w.writeBuffered()
+}
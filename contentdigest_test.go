@@ -0,0 +1,81 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestContentDigest(t *testing.T) {
+	t.Run("sets a SHA-256 Content-Digest header for the body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h := httph.ContentDigest(httph.ContentDigestOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"hello": "world"}`))
+		}))
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusCreated, res.Result().StatusCode)
+		// Example from https://www.rfc-editor.org/rfc/rfc9530#appendix-B.1
+		is.Equal(t, "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:", res.Result().Header.Get("Content-Digest"))
+		is.Equal(t, `{"hello": "world"}`, res.Body.String())
+	})
+
+	t.Run("skips the header for responses larger than the max size", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h := httph.ContentDigest(httph.ContentDigestOptions{MaxSizeBytes: 4})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("abc"))
+			_, _ = w.Write([]byte("def"))
+		}))
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "", res.Result().Header.Get("Content-Digest"))
+		is.Equal(t, "abcdef", res.Body.String())
+	})
+
+	t.Run("skips the header for flushed responses", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h := httph.ContentDigest(httph.ContentDigestOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("abc"))
+			w.(http.Flusher).Flush()
+			_, _ = w.Write([]byte("def"))
+		}))
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, "", res.Result().Header.Get("Content-Digest"))
+		is.Equal(t, "abcdef", res.Body.String())
+		is.True(t, res.Flushed)
+	})
+
+	t.Run("skips HEAD requests", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodHead, "/", nil)
+		res := httptest.NewRecorder()
+
+		h := httph.ContentDigest(httph.ContentDigestOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, "", res.Result().Header.Get("Content-Digest"))
+	})
+
+	t.Run("digests an empty body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", strings.NewReader(""))
+		res := httptest.NewRecorder()
+
+		h := httph.ContentDigest(httph.ContentDigestOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, "sha-256=:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=:", res.Result().Header.Get("Content-Digest"))
+	})
+}
@@ -0,0 +1,21 @@
+package httph
+
+import (
+	"mime"
+	"net/http"
+	"path"
+)
+
+// DetectContentType for a file response, given its name and up to the first 512 bytes of content.
+// The file extension is preferred, through mime.TypeByExtension, because content sniffing can't tell apart
+// many text formats like CSS and JavaScript.
+// If the extension is unknown, this falls back to sniffing the content with http.DetectContentType.
+// To override the detected type, just set the Content-Type header yourself.
+func DetectContentType(filename string, peek []byte) string {
+	if ext := path.Ext(filename); ext != "" {
+		if t := mime.TypeByExtension(ext); t != "" {
+			return t
+		}
+	}
+	return http.DetectContentType(peek)
+}
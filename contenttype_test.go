@@ -0,0 +1,35 @@
+package httph_test
+
+import (
+	"mime"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestDetectContentType(t *testing.T) {
+	// Use a custom extension, because system MIME files can change the types of common extensions
+	is.NotError(t, mime.AddExtensionType(".httph", "application/x-httph"))
+
+	tests := []struct {
+		name     string
+		filename string
+		peek     []byte
+		expected string
+	}{
+		{"uses the extension", "app.httph", []byte("hello"), "application/x-httph"},
+		{"uses the extension case-insensitively", "APP.HTTPH", nil, "application/x-httph"},
+		{"uses the extension of files in directories", "static/app.httph", nil, "application/x-httph"},
+		{"sniffs the content for unknown extensions", "file.unknown", []byte("\x89PNG\r\n\x1a\n"), "image/png"},
+		{"sniffs the content without extension", "README", []byte("hello"), "text/plain; charset=utf-8"},
+		{"falls back to octet-stream", "data", []byte{0, 1, 2}, "application/octet-stream"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			is.Equal(t, test.expected, httph.DetectContentType(test.filename, test.peek))
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package httph
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions for the CORS Middleware.
+type CORSOptions struct {
+	// AllowedOrigins, like "https://example.com", or "*" for any origin. Required.
+	AllowedOrigins []string
+
+	// AllowedMethods for cross-origin requests. Defaults to GET, HEAD, and POST.
+	AllowedMethods []string
+
+	// AllowedHeaders that clients can send in cross-origin requests, or "*" for any header.
+	// Headers are matched case-insensitively. None by default.
+	AllowedHeaders []string
+
+	// ExposedHeaders that clients can read from cross-origin responses. None by default.
+	ExposedHeaders []string
+
+	// AllowCredentials, like cookies, in cross-origin requests.
+	// Can't be combined with the "*" origin, because that would let any site make authenticated requests.
+	AllowCredentials bool
+
+	// MaxAge for clients to cache preflight responses. Zero omits the header, so the client default is used.
+	MaxAge time.Duration
+}
+
+// CORS is Middleware for cross-origin resource sharing.
+// Preflight requests, which are OPTIONS requests with an Access-Control-Request-Method header, are answered with
+// http.StatusNoContent and the Access-Control-* headers, and are not passed on to the next handler.
+// Other requests from allowed origins get the Access-Control-Allow-Origin header, and are passed on.
+// Requests from other origins are passed on without CORS headers, so the browser blocks the response.
+// See https://developer.mozilla.org/en-US/docs/Web/HTTP/CORS
+func CORS(opts CORSOptions) Middleware {
+	if len(opts.AllowedOrigins) == 0 {
+		panic("no allowed origins")
+	}
+
+	var anyOrigin bool
+	origins := map[string]struct{}{}
+	for _, o := range opts.AllowedOrigins {
+		if o == "*" {
+			anyOrigin = true
+			continue
+		}
+		if o == "" {
+			panic("invalid allowed origin")
+		}
+		origins[strings.ToLower(o)] = struct{}{}
+	}
+	if anyOrigin && opts.AllowCredentials {
+		panic("credentials can't be allowed for any origin")
+	}
+
+	if len(opts.AllowedMethods) == 0 {
+		opts.AllowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	methods := map[string]struct{}{}
+	for _, m := range opts.AllowedMethods {
+		methods[m] = struct{}{}
+	}
+
+	var anyHeader bool
+	headers := map[string]struct{}{}
+	for _, h := range opts.AllowedHeaders {
+		if h == "*" {
+			anyHeader = true
+			continue
+		}
+		headers[http.CanonicalHeaderKey(h)] = struct{}{}
+	}
+
+	allowMethods := strings.Join(opts.AllowedMethods, ", ")
+	exposeHeaders := strings.Join(opts.ExposedHeaders, ", ")
+	var maxAge string
+	if opts.MaxAge > 0 {
+		maxAge = strconv.FormatInt(int64(opts.MaxAge.Seconds()), 10)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			w.Header().Add("Vary", "Origin")
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			origin := r.Header.Get("Origin")
+			_, allowed := origins[strings.ToLower(origin)]
+			allowed = origin != "" && (allowed || anyOrigin)
+
+			if !preflight {
+				if allowed {
+					setAllowOrigin(w, origin, anyOrigin, opts.AllowCredentials)
+					if exposeHeaders != "" {
+						w.Header().Set("Access-Control-Expose-Headers", exposeHeaders)
+					}
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !allowed {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if _, ok := methods[r.Header.Get("Access-Control-Request-Method")]; !ok {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			var requestHeaders []string
+			for _, v := range r.Header.Values("Access-Control-Request-Headers") {
+				for _, h := range strings.Split(v, ",") {
+					h = strings.TrimSpace(h)
+					if h == "" {
+						continue
+					}
+					if _, ok := headers[http.CanonicalHeaderKey(h)]; !ok && !anyHeader {
+						w.WriteHeader(http.StatusNoContent)
+						return
+					}
+					requestHeaders = append(requestHeaders, h)
+				}
+			}
+
+			setAllowOrigin(w, origin, anyOrigin, opts.AllowCredentials)
+			w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+			if len(requestHeaders) > 0 {
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(requestHeaders, ", "))
+			}
+			if maxAge != "" {
+				w.Header().Set("Access-Control-Max-Age", maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// setAllowOrigin sets the Access-Control-Allow-Origin header to "*" if any origin is allowed,
+// and to the request origin otherwise.
+func setAllowOrigin(w http.ResponseWriter, origin string, anyOrigin, credentials bool) {
+	if anyOrigin {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if credentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
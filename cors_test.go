@@ -0,0 +1,110 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestCORS(t *testing.T) {
+	h := func(opts httph.CORSOptions) http.Handler {
+		return httph.CORS(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("Yo"))
+		}))
+	}
+
+	preflight := func(origin, method, headers string) *http.Request {
+		req := httptest.NewRequest(http.MethodOptions, "/", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			req.Header.Set("Access-Control-Request-Headers", headers)
+		}
+		return req
+	}
+
+	t.Run("answers a preflight request with the CORS headers", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		h(httph.CORSOptions{
+			AllowedOrigins:   []string{"https://example.com"},
+			AllowedMethods:   []string{http.MethodGet, http.MethodPut},
+			AllowedHeaders:   []string{"content-type", "Authorization"},
+			AllowCredentials: true,
+			MaxAge:           time.Hour,
+		}).ServeHTTP(res, preflight("https://example.com", http.MethodPut, "Content-Type, authorization"))
+
+		is.Equal(t, http.StatusNoContent, res.Code)
+		is.Equal(t, "https://example.com", res.Header().Get("Access-Control-Allow-Origin"))
+		is.Equal(t, "GET, PUT", res.Header().Get("Access-Control-Allow-Methods"))
+		is.Equal(t, "Content-Type, authorization", res.Header().Get("Access-Control-Allow-Headers"))
+		is.Equal(t, "true", res.Header().Get("Access-Control-Allow-Credentials"))
+		is.Equal(t, "3600", res.Header().Get("Access-Control-Max-Age"))
+		is.Equal(t, "", res.Body.String())
+	})
+
+	t.Run("does not allow preflight requests from other origins, or for other methods and headers", func(t *testing.T) {
+		opts := httph.CORSOptions{AllowedOrigins: []string{"https://example.com"}, AllowedHeaders: []string{"Content-Type"}}
+
+		for _, req := range []*http.Request{
+			preflight("https://evil.example.com", http.MethodGet, ""),
+			preflight("https://example.com", http.MethodDelete, ""),
+			preflight("https://example.com", http.MethodGet, "Content-Type, X-Custom"),
+		} {
+			res := httptest.NewRecorder()
+			h(opts).ServeHTTP(res, req)
+
+			is.Equal(t, http.StatusNoContent, res.Code)
+			is.Equal(t, "", res.Header().Get("Access-Control-Allow-Origin"))
+			is.Equal(t, "", res.Header().Get("Access-Control-Allow-Methods"))
+		}
+	})
+
+	t.Run("adds the allow origin header to a simple cross-origin GET", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", "https://example.com")
+		res := httptest.NewRecorder()
+		h(httph.CORSOptions{
+			AllowedOrigins: []string{"https://example.com"},
+			ExposedHeaders: []string{"X-Request-ID"},
+		}).ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Code)
+		is.Equal(t, "https://example.com", res.Header().Get("Access-Control-Allow-Origin"))
+		is.Equal(t, "X-Request-ID", res.Header().Get("Access-Control-Expose-Headers"))
+		is.Equal(t, "Origin", res.Header().Get("Vary"))
+		is.Equal(t, "", res.Header().Get("Access-Control-Allow-Credentials"))
+		is.Equal(t, "Yo", res.Body.String())
+	})
+
+	t.Run("allows any origin with a wildcard", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", "https://example.com")
+		res := httptest.NewRecorder()
+		h(httph.CORSOptions{AllowedOrigins: []string{"*"}}).ServeHTTP(res, req)
+
+		is.Equal(t, "*", res.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("passes requests from other origins on without CORS headers", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", "https://evil.example.com")
+		res := httptest.NewRecorder()
+		h(httph.CORSOptions{AllowedOrigins: []string{"https://example.com"}}).ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Code)
+		is.Equal(t, "", res.Header().Get("Access-Control-Allow-Origin"))
+		is.Equal(t, "Yo", res.Body.String())
+	})
+
+	t.Run("panics on credentials with a wildcard origin", func(t *testing.T) {
+		defer func() {
+			is.Equal(t, any("credentials can't be allowed for any origin"), recover())
+		}()
+		httph.CORS(httph.CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+	})
+}
@@ -0,0 +1,88 @@
+package httph
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+)
+
+// CSRFToken returns an anti-CSRF token bound to the session, using the synchronizer token pattern.
+// Include it in forms, or send it in a request header with JavaScript, and check it with ValidateCSRFToken or CSRF.
+// The token is an HMAC over the session ID and a per-session key, so it changes when the session ID is regenerated,
+// like on login, or when the token is rotated with RotateCSRFToken.
+// Getting a token makes sure the session is saved, so the token stays valid for the next request.
+func CSRFToken(s *Session) string {
+	if s.csrfKey == "" {
+		RotateCSRFToken(s)
+	}
+
+	mac := hmac.New(sha256.New, s.secret)
+	_, _ = mac.Write([]byte("csrf|" + s.id + "|" + s.csrfKey))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// RotateCSRFToken for the session, invalidating previous tokens.
+func RotateCSRFToken(s *Session) {
+	s.csrfKey = newSessionID()
+	s.dirty = true
+}
+
+// ValidateCSRFToken for the session, comparing in constant time.
+func ValidateCSRFToken(s *Session, token string) bool {
+	if s == nil || s.csrfKey == "" || token == "" {
+		return false
+	}
+	return hmac.Equal([]byte(CSRFToken(s)), []byte(token))
+}
+
+// CSRFOptions for the CSRF Middleware.
+type CSRFOptions struct {
+	// Header to read the token from. Defaults to "X-CSRF-Token".
+	Header string
+
+	// FormField to read the token from, if the header is not set. Defaults to "csrf_token".
+	FormField string
+}
+
+// CSRF is Middleware to enforce anti-CSRF tokens from CSRFToken on requests that are not safe,
+// that is, any other than GET, HEAD, OPTIONS, and TRACE.
+// The token is read from the header, or from the form field if the header is not set.
+// Requests with a missing or invalid token return http.StatusForbidden.
+// It must run after SessionMiddleware, and returns http.StatusInternalServerError if there is no session.
+func CSRF(opts CSRFOptions) Middleware {
+	if opts.Header == "" {
+		opts.Header = "X-CSRF-Token"
+	}
+	if opts.FormField == "" {
+		opts.FormField = "csrf_token"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			s := SessionFromContext(r.Context())
+			if s == nil {
+				http.Error(w, "no session", http.StatusInternalServerError)
+				return
+			}
+
+			token := r.Header.Get(opts.Header)
+			if token == "" {
+				token = r.PostFormValue(opts.FormField)
+			}
+
+			if !ValidateCSRFToken(s, token) {
+				http.Error(w, "invalid CSRF token", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestCSRF(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/form", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(httph.CSRFToken(httph.SessionFromContext(r.Context()))))
+	})
+	mux.HandleFunc("/submit", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/rotate", func(w http.ResponseWriter, r *http.Request) {
+		s := httph.SessionFromContext(r.Context())
+		httph.RotateCSRFToken(s)
+		_, _ = w.Write([]byte(httph.CSRFToken(s)))
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		httph.SessionFromContext(r.Context()).Regenerate()
+	})
+
+	h := httph.SessionMiddleware(httph.SessionOptions{Secret: []byte("supersecret")})(httph.CSRF(httph.CSRFOptions{})(mux))
+
+	getToken := func(t *testing.T, path string, cookies ...*http.Cookie) (string, *http.Cookie) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		cs := res.Result().Cookies()
+		if len(cs) == 0 {
+			return readBody(t, res), cookies[0]
+		}
+		return readBody(t, res), cs[0]
+	}
+
+	submit := func(token string, header bool, cookie *http.Cookie) int {
+		vs := url.Values{}
+		if !header {
+			vs.Set("csrf_token", token)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/submit", strings.NewReader(vs.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if header {
+			req.Header.Set("X-CSRF-Token", token)
+		}
+		req.AddCookie(cookie)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res.Result().StatusCode
+	}
+
+	t.Run("accepts a valid token from the form or the header", func(t *testing.T) {
+		token, cookie := getToken(t, "/form")
+
+		is.Equal(t, http.StatusOK, submit(token, false, cookie))
+		is.Equal(t, http.StatusOK, submit(token, true, cookie))
+	})
+
+	t.Run("rejects a missing or invalid token", func(t *testing.T) {
+		token, cookie := getToken(t, "/form")
+
+		is.Equal(t, http.StatusForbidden, submit("", true, cookie))
+		is.Equal(t, http.StatusForbidden, submit(token+"x", true, cookie))
+	})
+
+	t.Run("rejects a token from another session", func(t *testing.T) {
+		token, _ := getToken(t, "/form")
+		_, otherCookie := getToken(t, "/form")
+
+		is.Equal(t, http.StatusForbidden, submit(token, true, otherCookie))
+	})
+
+	t.Run("rejects the old token after rotation", func(t *testing.T) {
+		oldToken, cookie := getToken(t, "/form")
+		newToken, cookie := getToken(t, "/rotate", cookie)
+
+		is.True(t, oldToken != newToken)
+		is.Equal(t, http.StatusForbidden, submit(oldToken, true, cookie))
+		is.Equal(t, http.StatusOK, submit(newToken, true, cookie))
+	})
+
+	t.Run("rejects the old token after session regeneration", func(t *testing.T) {
+		oldToken, cookie := getToken(t, "/form")
+		_, cookie = getToken(t, "/login", cookie)
+
+		is.Equal(t, http.StatusForbidden, submit(oldToken, true, cookie))
+	})
+}
@@ -0,0 +1,66 @@
+package httph
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ErrInvalidCursor is wrapped by errors from DecodeCursor when the cursor is malformed or has been tampered with.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorError for DecodeCursor. It satisfies statusCodeGiver with http.StatusBadRequest.
+type cursorError struct {
+	reason string
+}
+
+func (e cursorError) Error() string {
+	return ErrInvalidCursor.Error() + ": " + e.reason
+}
+
+func (e cursorError) Unwrap() error {
+	return ErrInvalidCursor
+}
+
+func (e cursorError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// EncodeCursor v as an opaque pagination cursor token, which is URL-safe base64-encoded JSON.
+// If key is given, the token is signed with an HMAC, so DecodeCursor can detect tampering.
+// Note that the cursor is not encrypted, so clients can read its content.
+func EncodeCursor(v any, key []byte) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	if len(key) > 0 {
+		token = sign(key, token)
+	}
+	return token, nil
+}
+
+// DecodeCursor from a token created with EncodeCursor into dst, which must be a pointer.
+// If key is given, the token signature is verified with it.
+// Malformed and tampered tokens result in an error wrapping ErrInvalidCursor,
+// which also satisfies statusCodeGiver with http.StatusBadRequest, for returning directly from JSONHandler.
+func DecodeCursor(token string, dst any, key []byte) error {
+	if len(key) > 0 {
+		var ok bool
+		token, ok = verifySigned(key, token)
+		if !ok {
+			return cursorError{reason: "bad signature"}
+		}
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursorError{reason: "bad encoding"}
+	}
+	if err := json.Unmarshal(b, dst); err != nil {
+		return cursorError{reason: "bad content"}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package httph_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+type cursor struct {
+	LastID int
+	Sort   string
+}
+
+func TestEncodeCursor(t *testing.T) {
+	t.Run("round-trips without a key", func(t *testing.T) {
+		token, err := httph.EncodeCursor(cursor{LastID: 42, Sort: "name"}, nil)
+		is.NotError(t, err)
+		is.Equal(t, "eyJMYXN0SUQiOjQyLCJTb3J0IjoibmFtZSJ9", token)
+
+		var c cursor
+		is.NotError(t, httph.DecodeCursor(token, &c, nil))
+		is.Equal(t, cursor{LastID: 42, Sort: "name"}, c)
+	})
+
+	t.Run("round-trips with a key", func(t *testing.T) {
+		key := []byte("secret")
+		token, err := httph.EncodeCursor(cursor{LastID: 42}, key)
+		is.NotError(t, err)
+
+		var c cursor
+		is.NotError(t, httph.DecodeCursor(token, &c, key))
+		is.Equal(t, 42, c.LastID)
+	})
+
+	t.Run("returns an invalid cursor error on tampering", func(t *testing.T) {
+		key := []byte("secret")
+		token, err := httph.EncodeCursor(cursor{LastID: 42}, key)
+		is.NotError(t, err)
+
+		tampered, err := httph.EncodeCursor(cursor{LastID: 43}, nil)
+		is.NotError(t, err)
+
+		var c cursor
+		for _, token := range []string{tampered, tampered + token[len(tampered):], "", "."} {
+			err = httph.DecodeCursor(token, &c, key)
+			is.True(t, errors.Is(err, httph.ErrInvalidCursor))
+		}
+
+		err = httph.DecodeCursor(token, &c, []byte("other"))
+		is.True(t, errors.Is(err, httph.ErrInvalidCursor))
+	})
+
+	t.Run("returns an invalid cursor error with bad request status on malformed tokens", func(t *testing.T) {
+		var c cursor
+		for _, token := range []string{"not base64!", "bm90IGpzb24"} {
+			err := httph.DecodeCursor(token, &c, nil)
+			is.True(t, errors.Is(err, httph.ErrInvalidCursor))
+
+			var scg interface{ StatusCode() int }
+			is.True(t, errors.As(err, &scg))
+			is.Equal(t, http.StatusBadRequest, scg.StatusCode())
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package httph
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Deprecated is Middleware to signal to clients that the wrapped routes are deprecated.
+// It sets the Deprecation header to "true", the Sunset header to the given time as an HTTP-date,
+// and a Link header with rel="deprecation" pointing to docsURL.
+// A zero sunset time omits the Sunset header, and an empty docsURL omits the Link header.
+// See https://www.rfc-editor.org/rfc/rfc8594 and https://www.rfc-editor.org/rfc/rfc9745
+func Deprecated(sunset time.Time, docsURL string) Middleware {
+	var sunsetValue string
+	if !sunset.IsZero() {
+		sunsetValue = sunset.UTC().Format(http.TimeFormat)
+	}
+
+	var linkValue string
+	if docsURL != "" {
+		linkValue = fmt.Sprintf(`<%v>; rel="deprecation"`, docsURL)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			if sunsetValue != "" {
+				w.Header().Set("Sunset", sunsetValue)
+			}
+			if linkValue != "" {
+				w.Header().Add("Link", linkValue)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestDeprecated(t *testing.T) {
+	t.Run("sets Deprecation, Sunset, and Link headers", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		sunset := time.Date(2030, 1, 2, 4, 5, 6, 0, time.FixedZone("CET", 60*60))
+		h := httph.Deprecated(sunset, "https://example.com/docs/v2")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "true", res.Result().Header.Get("Deprecation"))
+		is.Equal(t, "Wed, 02 Jan 2030 03:05:06 GMT", res.Result().Header.Get("Sunset"))
+		is.Equal(t, `<https://example.com/docs/v2>; rel="deprecation"`, res.Result().Header.Get("Link"))
+	})
+
+	t.Run("omits Sunset and Link headers when not given", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h := httph.Deprecated(time.Time{}, "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, "true", res.Result().Header.Get("Deprecation"))
+		is.Equal(t, 0, len(res.Result().Header.Values("Sunset")))
+		is.Equal(t, 0, len(res.Result().Header.Values("Link")))
+	})
+}
@@ -0,0 +1,33 @@
+package httph
+
+import (
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+)
+
+var errorLogger atomic.Pointer[slog.Logger]
+
+// SetErrorLogger for errors returned from handler functions in JSONHandler, NegotiatedHandler, ErrorHandler,
+// and RenderError. Errors resulting in a 5xx status code are logged at the error level with the request method and path,
+// the status code, and the full error, including any underlying error wrapped in an HTTPError,
+// before the response is written. The response itself is unchanged.
+// A nil logger disables logging, which is the default.
+// SetErrorLogger is safe to call concurrently with handling requests.
+func SetErrorLogger(log *slog.Logger) {
+	errorLogger.Store(log)
+}
+
+// logError from a handler function if an error logger is set and the status code is 5xx.
+func logError(r *http.Request, code int, err error) {
+	log := errorLogger.Load()
+	if log == nil || code < 500 {
+		return
+	}
+	log.LogAttrs(r.Context(), slog.LevelError, "Error handling HTTP request",
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.Int("status", code),
+		slog.String("error", err.Error()),
+	)
+}
@@ -0,0 +1,77 @@
+package httph_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestSetErrorLogger(t *testing.T) {
+	setLogger := func(t *testing.T) *bytes.Buffer {
+		t.Helper()
+		var b bytes.Buffer
+		httph.SetErrorLogger(slog.New(slog.NewTextHandler(&b, &slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		})))
+		t.Cleanup(func() {
+			httph.SetErrorLogger(nil)
+		})
+		return &b
+	}
+
+	t.Run("logs 5xx errors from JSONHandler, and writes the same response", func(t *testing.T) {
+		b := setLogger(t)
+
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
+			return nil, httph.NewHTTPError(http.StatusServiceUnavailable, errors.New("database is down"))
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/users", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusServiceUnavailable, res.Code)
+		is.Equal(t, `{"Error":"Service Unavailable"}`, readBody(t, res))
+		is.Equal(t, `level=ERROR msg="Error handling HTTP request" method=POST path=/users status=503 error="503 Service Unavailable: database is down"`+"\n", b.String())
+	})
+
+	t.Run("logs 5xx errors from ErrorHandler", func(t *testing.T) {
+		b := setLogger(t)
+
+		h := httph.ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+			return errors.New("oh no")
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusInternalServerError, res.Code)
+		is.Equal(t, "oh no", readBody(t, res))
+		is.Equal(t, `level=ERROR msg="Error handling HTTP request" method=GET path=/ status=500 error="oh no"`+"\n", b.String())
+	})
+
+	t.Run("does not log 4xx errors", func(t *testing.T) {
+		b := setLogger(t)
+
+		h := httph.ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+			return httph.HTTPError{Code: http.StatusNotFound}
+		})
+
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		is.Equal(t, "", b.String())
+	})
+}
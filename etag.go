@@ -0,0 +1,85 @@
+package httph
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// ETagOptions for ETagWithOptions.
+type ETagOptions struct {
+	// MaxSizeBytes of the response body to buffer for the ETag. Defaults to 1 MiB.
+	MaxSizeBytes int64
+}
+
+// ETag is Middleware to set a strong ETag header from a hash of the response body, and to respond with
+// http.StatusNotModified and an empty body if the request If-None-Match header matches it.
+// Only successful responses to GET and HEAD requests get an ETag, and the status code and headers are kept otherwise.
+// An ETag set by the handler is kept and used for the comparison.
+// Because the ETag must be sent before the body, the response is buffered in memory, up to 1 MiB per request.
+// Larger responses, or responses that are flushed by the handler, are streamed without the ETag.
+// See ETagWithOptions to configure the limit.
+func ETag(next http.Handler) http.Handler {
+	return ETagWithOptions(ETagOptions{})(next)
+}
+
+// ETagWithOptions is like ETag, with options.
+func ETagWithOptions(opts ETagOptions) Middleware {
+	if opts.MaxSizeBytes <= 0 {
+		opts.MaxSizeBytes = 1 << 20
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			bw := &bufferedResponseWriter{ResponseWriter: w, max: opts.MaxSizeBytes}
+			next.ServeHTTP(bw, r)
+			finishETag(bw, r)
+		})
+	}
+}
+
+// finishETag sets the ETag header, and writes either the buffered response or http.StatusNotModified.
+func finishETag(w *bufferedResponseWriter, r *http.Request) {
+	if !w.buffered() {
+		return
+	}
+
+	// HEAD responses usually have no body, so there's nothing to hash
+	successful := w.code >= 200 && w.code < 300 && w.code != http.StatusNoContent
+	if successful && w.Header().Get("ETag") == "" && (r.Method == http.MethodGet || w.buf.Len() > 0) {
+		hash := sha256.Sum256(w.buf.Bytes())
+		w.Header().Set("ETag", `"`+base64.RawURLEncoding.EncodeToString(hash[:16])+`"`)
+	}
+
+	if etag := w.Header().Get("ETag"); successful && etag != "" && ifNoneMatch(r.Header.Get("If-None-Match"), etag) {
+		for _, k := range []string{"Content-Type", "Content-Length", "Content-Encoding"} {
+			w.Header().Del(k)
+		}
+		w.ResponseWriter.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.writeBuffered()
+}
+
+// ifNoneMatch returns whether the If-None-Match header value matches the etag, with weak comparison.
+// See https://www.rfc-editor.org/rfc/rfc9110#field.if-none-match
+func ifNoneMatch(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
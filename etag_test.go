@@ -0,0 +1,108 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestETag(t *testing.T) {
+	h := httph.ETag(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Custom", "yo")
+		_, _ = w.Write([]byte("Hello"))
+	}))
+
+	t.Run("sets a strong ETag header and keeps the response", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		etag := res.Result().Header.Get("ETag")
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.True(t, strings.HasPrefix(etag, `"`) && strings.HasSuffix(etag, `"`) && len(etag) > 2)
+		is.Equal(t, "text/plain", res.Result().Header.Get("Content-Type"))
+		is.Equal(t, "yo", res.Result().Header.Get("X-Custom"))
+		is.Equal(t, "Hello", res.Body.String())
+	})
+
+	t.Run("responds with not modified if the ETag matches", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		etag := res.Result().Header.Get("ETag")
+
+		for _, ifNoneMatch := range []string{etag, `"other", ` + etag, "W/" + etag, "*"} {
+			req = httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("If-None-Match", ifNoneMatch)
+			res = httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+
+			is.Equal(t, http.StatusNotModified, res.Result().StatusCode)
+			is.Equal(t, etag, res.Result().Header.Get("ETag"))
+			is.Equal(t, "", res.Body.String())
+		}
+	})
+
+	t.Run("responds in full if the ETag does not match", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("If-None-Match", `"other"`)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "Hello", res.Body.String())
+	})
+
+	t.Run("does not set the ETag for unsuccessful responses or other methods", func(t *testing.T) {
+		h := httph.ETag(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				w.WriteHeader(http.StatusNotFound)
+			}
+			_, _ = w.Write([]byte("Hello"))
+		}))
+
+		for _, method := range []string{http.MethodGet, http.MethodPost} {
+			req := httptest.NewRequest(method, "/", nil)
+			req.Header.Set("If-None-Match", "*")
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+
+			is.Equal(t, "", res.Result().Header.Get("ETag"))
+			is.Equal(t, "Hello", res.Body.String())
+		}
+	})
+
+	t.Run("streams responses larger than the max size without the ETag", func(t *testing.T) {
+		h := httph.ETagWithOptions(httph.ETagOptions{MaxSizeBytes: 4})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("Hello"))
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, "", res.Result().Header.Get("ETag"))
+		is.Equal(t, "Hello", res.Body.String())
+	})
+
+	t.Run("uses an ETag set by the handler", func(t *testing.T) {
+		h := httph.ETag(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte("Hello"))
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("If-None-Match", `"v1"`)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusNotModified, res.Result().StatusCode)
+		is.Equal(t, `"v1"`, res.Result().Header.Get("ETag"))
+	})
+}
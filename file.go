@@ -0,0 +1,102 @@
+package httph
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+)
+
+// FileHandler serves the file with the given name from fsys, like an embed.FS or os.DirFS,
+// with the error conventions of ErrorHandler.
+// A missing file results in http.StatusNotFound, and a file that can't be opened because of permissions
+// in http.StatusForbidden.
+// The content type is detected with DetectContentType, unless the Content-Type header is already set.
+// If the file supports seeking, like files from embed.FS and os.DirFS do, it's served with http.ServeContent,
+// which handles range and conditional requests. Otherwise it's streamed as is.
+// If name is a directory, its index.html file is served if there is one, otherwise the result is http.StatusForbidden.
+func FileHandler(fsys fs.FS, name string) http.HandlerFunc {
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		f, info, err := openFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+
+		if info.IsDir() {
+			index, indexInfo, err := openFile(fsys, path.Join(name, "index.html"))
+			if err != nil {
+				if httpErr, ok := asHTTPError(err); ok && httpErr.Code == http.StatusNotFound {
+					return NewHTTPError(http.StatusForbidden, httpErr.Err)
+				}
+				return err
+			}
+			_ = f.Close()
+			f, info = index, indexInfo
+			if info.IsDir() {
+				return HTTPError{Code: http.StatusForbidden}
+			}
+		}
+
+		return serveFile(w, r, f, info, info.Name())
+	})
+}
+
+// serveFile with the content type detected for name if it's not set, with http.ServeContent if f is seekable.
+func serveFile(w http.ResponseWriter, r *http.Request, f fs.File, info fs.FileInfo, name string) error {
+	peek := make([]byte, 512)
+	n, err := io.ReadFull(f, peek)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", DetectContentType(name, peek[:n]))
+	}
+
+	if rs, ok := f.(io.ReadSeeker); ok {
+		if _, err := rs.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		http.ServeContent(w, r, name, info.ModTime(), rs)
+		return nil
+	}
+
+	if !info.ModTime().IsZero() {
+		w.Header().Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+	}
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	_, _ = w.Write(peek[:n])
+	_, _ = io.Copy(w, f)
+	return nil
+}
+
+// openFile and stat it, mapping fs errors to HTTPError.
+func openFile(fsys fs.FS, name string) (fs.File, fs.FileInfo, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, fsError(err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, fsError(err)
+	}
+	return f, info, nil
+}
+
+// fsError maps err to an HTTPError wrapping it, so the cause is still there for logging.
+func fsError(err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrInvalid):
+		return NewHTTPError(http.StatusNotFound, err)
+	case errors.Is(err, fs.ErrPermission):
+		return NewHTTPError(http.StatusForbidden, err)
+	default:
+		return err
+	}
+}
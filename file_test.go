@@ -0,0 +1,135 @@
+package httph_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestErrorHandler(t *testing.T) {
+	t.Run("writes nothing extra on nil error", func(t *testing.T) {
+		h := httph.ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+			_, _ = w.Write([]byte("yo"))
+			return nil
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "yo", readBody(t, res))
+	})
+
+	t.Run("uses the status code from the error", func(t *testing.T) {
+		h := httph.ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+			return httph.HTTPError{Code: http.StatusTeapot}
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusTeapot, res.Result().StatusCode)
+		is.Equal(t, "I'm a teapot", readBody(t, res))
+	})
+
+	t.Run("uses the message from the error", func(t *testing.T) {
+		h := httph.ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+			return httph.HTTPError{Code: http.StatusTooManyRequests, Message: "your quota is exhausted"}
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusTooManyRequests, res.Result().StatusCode)
+		is.Equal(t, "your quota is exhausted", readBody(t, res))
+	})
+
+	t.Run("defaults to internal server error", func(t *testing.T) {
+		h := httph.ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+			return errors.New("oh no")
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusInternalServerError, res.Result().StatusCode)
+		is.Equal(t, "oh no", readBody(t, res))
+	})
+}
+
+func TestFileHandler(t *testing.T) {
+	fsys := fstest.MapFS{
+		"style.css":       {Data: []byte("body { color: red; }")},
+		"docs/index.html": {Data: []byte("<p>Docs</p>")},
+		"empty/.gitkeep":  {Data: nil},
+	}
+
+	t.Run("serves a file with detected content type", func(t *testing.T) {
+		h := httph.FileHandler(fsys, "style.css")
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "text/css; charset=utf-8", res.Header().Get("Content-Type"))
+		is.Equal(t, "body { color: red; }", readBody(t, res))
+	})
+
+	t.Run("keeps a content type set by the caller", func(t *testing.T) {
+		h := httph.FileHandler(fsys, "style.css")
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "text/plain; charset=utf-8", res.Header().Get("Content-Type"))
+		is.Equal(t, "body { color: red; }", readBody(t, res))
+	})
+
+	t.Run("returns not found for a missing file", func(t *testing.T) {
+		h := httph.FileHandler(fsys, "missing.css")
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusNotFound, res.Result().StatusCode)
+		is.Equal(t, "Not Found", readBody(t, res))
+	})
+
+	t.Run("serves the index file for a directory", func(t *testing.T) {
+		h := httph.FileHandler(fsys, "docs")
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "text/html; charset=utf-8", res.Header().Get("Content-Type"))
+		is.Equal(t, "<p>Docs</p>", readBody(t, res))
+	})
+
+	t.Run("returns forbidden for a directory without an index file", func(t *testing.T) {
+		h := httph.FileHandler(fsys, "empty")
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusForbidden, res.Result().StatusCode)
+		is.Equal(t, "Forbidden", readBody(t, res))
+	})
+}
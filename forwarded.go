@@ -0,0 +1,90 @@
+package httph
+
+import (
+	"strings"
+)
+
+// ForwardedElement is a single element of a Forwarded header, added by one proxy.
+// Values are unquoted, but otherwise as given, so For and By can be IP addresses with optional ports,
+// IPv6 literals in brackets like "[2001:db8:cafe::17]:4711", obfuscated identifiers like "_hidden", or "unknown".
+type ForwardedElement struct {
+	For   string
+	By    string
+	Host  string
+	Proto string
+}
+
+// ParseForwarded parses the value of a Forwarded header into its elements, in order from the first proxy to the last.
+// Parameter names are case-insensitive, values can be tokens or quoted strings, and unknown parameters are ignored.
+// If the request has several Forwarded headers, join their values with a comma first.
+// See https://www.rfc-editor.org/rfc/rfc7239
+func ParseForwarded(header string) []ForwardedElement {
+	var elements []ForwardedElement
+	var current ForwardedElement
+	var hasPairs bool
+
+	var key, value strings.Builder
+	inValue, inQuotes, quoted, escaped := false, false, false, false
+
+	endPair := func() {
+		k := strings.ToLower(strings.TrimSpace(key.String()))
+		v := value.String()
+		if !quoted {
+			v = strings.TrimSpace(v)
+		}
+		key.Reset()
+		value.Reset()
+		inValue, quoted = false, false
+
+		switch k {
+		case "for":
+			current.For = v
+		case "by":
+			current.By = v
+		case "host":
+			current.Host = v
+		case "proto":
+			current.Proto = strings.ToLower(v)
+		default:
+			return
+		}
+		hasPairs = true
+	}
+
+	endElement := func() {
+		endPair()
+		if hasPairs {
+			elements = append(elements, current)
+		}
+		current = ForwardedElement{}
+		hasPairs = false
+	}
+
+	for _, c := range header {
+		switch {
+		case escaped:
+			value.WriteRune(c)
+			escaped = false
+		case inQuotes && c == '\\':
+			escaped = true
+		case c == '"' && inValue:
+			inQuotes = !inQuotes
+			quoted = true
+		case inQuotes:
+			value.WriteRune(c)
+		case c == '=' && !inValue:
+			inValue = true
+		case c == ';':
+			endPair()
+		case c == ',':
+			endElement()
+		case inValue:
+			value.WriteRune(c)
+		default:
+			key.WriteRune(c)
+		}
+	}
+	endElement()
+
+	return elements
+}
@@ -0,0 +1,43 @@
+package httph_test
+
+import (
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestParseForwarded(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected []httph.ForwardedElement
+	}{
+		{"parses an obfuscated identifier", `for="_gazonk"`, []httph.ForwardedElement{{For: "_gazonk"}}},
+		{"parses a quoted IPv6 literal with port and case-insensitive name", `For="[2001:db8:cafe::17]:4711"`,
+			[]httph.ForwardedElement{{For: "[2001:db8:cafe::17]:4711"}}},
+		{"parses several parameters", `for=192.0.2.60;proto=http;by=203.0.113.43`,
+			[]httph.ForwardedElement{{For: "192.0.2.60", Proto: "http", By: "203.0.113.43"}}},
+		{"parses several elements", `for=192.0.2.43, for=198.51.100.17`,
+			[]httph.ForwardedElement{{For: "192.0.2.43"}, {For: "198.51.100.17"}}},
+		{"parses unknown", `for=unknown;host=example.com`, []httph.ForwardedElement{{For: "unknown", Host: "example.com"}}},
+		{"parses commas and semicolons in quoted strings", `for="a,b;c";host="example.com", for=d`,
+			[]httph.ForwardedElement{{For: "a,b;c", Host: "example.com"}, {For: "d"}}},
+		{"parses escaped quotes", `for="a\"b"`, []httph.ForwardedElement{{For: `a"b`}}},
+		{"keeps spaces in quoted strings", `for=" a "; host = example.com `, []httph.ForwardedElement{{For: " a ", Host: "example.com"}}},
+		{"ignores unknown parameters and empty elements", `secret=abc;for=1.2.3.4,,foo=bar`,
+			[]httph.ForwardedElement{{For: "1.2.3.4"}}},
+		{"parses an empty header", "", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := httph.ParseForwarded(test.header)
+			is.Equal(t, len(test.expected), len(actual))
+			for i := range test.expected {
+				is.Equal(t, test.expected[i], actual[i])
+			}
+		})
+	}
+}
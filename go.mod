@@ -1,6 +1,6 @@
 module maragu.dev/httph
 
-go 1.19
+go 1.21
 
 require (
 	github.com/maragudk/is v0.1.0
@@ -0,0 +1,84 @@
+package httph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// HealthCheck for Health, with a name to report if it fails.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+var errHealthCheckUnfinished = errors.New("check didn't finish in time")
+
+// Health returns a handler for health checks from load balancers and uptime monitors.
+// The checks run concurrently with the request context, so they should respect its deadline.
+// If all checks pass, the response is http.StatusOK with {"status":"ok"}.
+// If any fail, or haven't finished when the request context is done, the response is
+// http.StatusServiceUnavailable with {"status":"error","failed":[...]}, with the names of the failing checks
+// in the order they were given. The check errors aren't included in the response, but are logged
+// if an error logger is set with SetErrorLogger.
+func Health(checks ...HealthCheck) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type result struct {
+			i   int
+			err error
+		}
+		results := make(chan result, len(checks))
+		for i, c := range checks {
+			go func(i int, c HealthCheck) {
+				results <- result{i: i, err: c.Check(r.Context())}
+			}(i, c)
+		}
+
+		errs := make([]error, len(checks))
+		for i := range errs {
+			errs[i] = errHealthCheckUnfinished
+		}
+	loop:
+		for range checks {
+			select {
+			case res := <-results:
+				errs[res.i] = res.err
+			case <-r.Context().Done():
+				break loop
+			}
+		}
+		// Keep results that were ready when the context was done
+		for len(results) > 0 {
+			res := <-results
+			errs[res.i] = res.err
+		}
+
+		var failed []string
+		var failedErrs []error
+		for i, c := range checks {
+			if errs[i] != nil {
+				failed = append(failed, c.Name)
+				failedErrs = append(failedErrs, fmt.Errorf("%v: %w", c.Name, errs[i]))
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+
+		if len(failed) == 0 {
+			w.WriteHeader(http.StatusOK)
+			writeResponse(w, healthResponse{Status: "ok"})
+			return
+		}
+
+		logError(r, http.StatusServiceUnavailable, fmt.Errorf("health checks failed: %w", errors.Join(failedErrs...)))
+		w.WriteHeader(http.StatusServiceUnavailable)
+		writeResponse(w, healthResponse{Status: "error", Failed: failed})
+	}
+}
+
+type healthResponse struct {
+	Status string   `json:"status"`
+	Failed []string `json:"failed,omitempty"`
+}
@@ -0,0 +1,75 @@
+package httph_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestHealth(t *testing.T) {
+	pass := func(ctx context.Context) error { return nil }
+
+	t.Run("returns ok if all checks pass", func(t *testing.T) {
+		h := httph.Health(
+			httph.HealthCheck{Name: "database", Check: pass},
+			httph.HealthCheck{Name: "cache", Check: pass},
+		)
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		is.Equal(t, http.StatusOK, res.Code)
+		is.Equal(t, "application/json", res.Header().Get("Content-Type"))
+		is.Equal(t, `{"status":"ok"}`+"\n", res.Body.String())
+	})
+
+	t.Run("returns ok without checks", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		httph.Health().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		is.Equal(t, http.StatusOK, res.Code)
+	})
+
+	t.Run("returns service unavailable with the failing check names", func(t *testing.T) {
+		h := httph.Health(
+			httph.HealthCheck{Name: "database", Check: func(ctx context.Context) error { return errors.New("connection refused") }},
+			httph.HealthCheck{Name: "cache", Check: pass},
+			httph.HealthCheck{Name: "queue", Check: func(ctx context.Context) error { return errors.New("oh no") }},
+		)
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		is.Equal(t, http.StatusServiceUnavailable, res.Code)
+		is.Equal(t, `{"status":"error","failed":["database","queue"]}`+"\n", res.Body.String())
+	})
+
+	t.Run("fails checks that don't finish before the request context deadline", func(t *testing.T) {
+		block := make(chan struct{})
+		defer close(block)
+
+		h := httph.Health(
+			httph.HealthCheck{Name: "database", Check: pass},
+			httph.HealthCheck{Name: "slow", Check: func(ctx context.Context) error {
+				<-block
+				return nil
+			}},
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		req := httptest.NewRequest(http.MethodGet, "/health", nil).WithContext(ctx)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusServiceUnavailable, res.Code)
+		is.Equal(t, `{"status":"error","failed":["slow"]}`+"\n", res.Body.String())
+	})
+}
@@ -0,0 +1,29 @@
+package httph
+
+import (
+	"net/http"
+	"strings"
+)
+
+// HostWithoutPort returns the request Host without any port, and lowercased.
+// Bracketed IPv6 literals like "[::1]:8080" are returned without the brackets, like "::1",
+// matching url.URL.Hostname.
+func HostWithoutPort(r *http.Request) string {
+	return stripPort(strings.ToLower(r.Host))
+}
+
+// stripPort from host, handling bracketed IPv6 literals.
+func stripPort(host string) string {
+	if strings.HasPrefix(host, "[") {
+		if i := strings.IndexByte(host, ']'); i > 0 {
+			return host[1:i]
+		}
+		return host
+	}
+
+	// More than one colon means an unbracketed IPv6 literal, which can't have a port
+	if i := strings.IndexByte(host, ':'); i >= 0 && strings.Count(host, ":") == 1 {
+		return host[:i]
+	}
+	return host
+}
@@ -0,0 +1,36 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestHostWithoutPort(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{"example.com", "example.com"},
+		{"example.com:8080", "example.com"},
+		{"Example.COM:443", "example.com"},
+		{"127.0.0.1:8080", "127.0.0.1"},
+		{"[::1]:8080", "::1"},
+		{"[::1]", "::1"},
+		{"[2001:db8::1]:443", "2001:db8::1"},
+		{"2001:db8::1", "2001:db8::1"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.host, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = test.host
+
+			is.Equal(t, test.expected, httph.HostWithoutPort(req))
+		})
+	}
+}
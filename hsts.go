@@ -0,0 +1,55 @@
+package httph
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HSTSOptions for the HSTS Middleware.
+type HSTSOptions struct {
+	// MaxAge for browsers to remember to only use HTTPS. Defaults to one year.
+	MaxAge time.Duration
+
+	// IncludeSubdomains applies the policy to all subdomains as well.
+	IncludeSubdomains bool
+
+	// Preload signals consent to be included in browser preload lists.
+	// See https://hstspreload.org
+	Preload bool
+
+	// Always sets the header, also for requests not served over TLS.
+	// Useful behind a TLS-terminating proxy that doesn't set X-Forwarded-Proto.
+	Always bool
+}
+
+// HSTS is Middleware to set the Strict-Transport-Security header, so browsers only use HTTPS for the site.
+// The header is only set for requests served over TLS, or with a X-Forwarded-Proto header of "https",
+// because browsers ignore it over plain HTTP. See HSTSOptions.Always to set it for all requests.
+// See https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Strict-Transport-Security
+func HSTS(opts HSTSOptions) Middleware {
+	if opts.MaxAge == 0 {
+		opts.MaxAge = 365 * 24 * time.Hour
+	}
+	if opts.MaxAge < 0 {
+		panic("invalid max age")
+	}
+
+	value := fmt.Sprintf("max-age=%d", int64(opts.MaxAge.Seconds()))
+	if opts.IncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if opts.Preload {
+		value += "; preload"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if opts.Always || r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+				w.Header().Set("Strict-Transport-Security", value)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
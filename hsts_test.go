@@ -0,0 +1,64 @@
+package httph_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestHSTS(t *testing.T) {
+	h := func(opts httph.HSTSOptions) http.Handler {
+		return httph.HSTS(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	}
+
+	tlsRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.TLS = &tls.ConnectionState{}
+		return req
+	}
+
+	t.Run("sets a max age of one year by default", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		h(httph.HSTSOptions{}).ServeHTTP(res, tlsRequest())
+
+		is.Equal(t, "max-age=31536000", res.Result().Header.Get("Strict-Transport-Security"))
+	})
+
+	t.Run("includes subdomains and preload", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		h(httph.HSTSOptions{MaxAge: 2 * 365 * 24 * time.Hour, IncludeSubdomains: true, Preload: true}).ServeHTTP(res, tlsRequest())
+
+		is.Equal(t, "max-age=63072000; includeSubDomains; preload", res.Result().Header.Get("Strict-Transport-Security"))
+	})
+
+	t.Run("does not set the header for requests not over TLS", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h(httph.HSTSOptions{}).ServeHTTP(res, req)
+
+		is.Equal(t, "", res.Result().Header.Get("Strict-Transport-Security"))
+	})
+
+	t.Run("sets the header for requests forwarded from HTTPS", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		res := httptest.NewRecorder()
+		h(httph.HSTSOptions{}).ServeHTTP(res, req)
+
+		is.Equal(t, "max-age=31536000", res.Result().Header.Get("Strict-Transport-Security"))
+	})
+
+	t.Run("always sets the header if forced", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h(httph.HSTSOptions{Always: true}).ServeHTTP(res, req)
+
+		is.Equal(t, "max-age=31536000", res.Result().Header.Get("Strict-Transport-Security"))
+	})
+}
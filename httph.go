@@ -4,14 +4,29 @@ package httph
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
 	"embed"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
+	"io/fs"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"path"
+	"reflect"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/mitchellh/mapstructure"
 )
@@ -20,34 +35,195 @@ type validator interface {
 	Validate() error
 }
 
+// contextValidator is like validator, but receives the request context, for validation that needs it,
+// like database lookups.
+type contextValidator interface {
+	Validate(ctx context.Context) error
+}
+
+// validate v with the contextValidator or validator interface, if it satisfies either.
+func validate(ctx context.Context, v any) error {
+	switch v := v.(type) {
+	case contextValidator:
+		return v.Validate(ctx)
+	case validator:
+		return v.Validate()
+	}
+	return nil
+}
+
+// FormHandlerOptions for FormHandler.
+type FormHandlerOptions struct {
+	// MaxFields is the maximum number of form values, counting each value of repeated keys. Defaults to 1000.
+	MaxFields int
+
+	// MaxMemoryBytes of multipart forms to keep in memory, with the rest of the files stored in temporary files.
+	// See http.Request.ParseMultipartForm. Defaults to 32 MiB.
+	// To limit the total size of the request body, use the maxSizeGiver interface.
+	MaxMemoryBytes int64
+
+	// TimeLayouts tried in order when parsing time.Time fields, after time.RFC3339, which is always tried first.
+	// For example, "2006-01-02" for date inputs.
+	TimeLayouts []string
+
+	// JSONErrors renders error responses as JSON, in the same format as JSONHandler, instead of plain text.
+	JSONErrors bool
+
+	// IncludeQuery decodes URL query parameters as well as the body, with body values taking precedence,
+	// so a field present in both is only decoded from the body.
+	// Without it, values are decoded from http.Request.Form, which has the query parameters after the body values,
+	// so a field present in both gets both values.
+	IncludeQuery bool
+
+	// NegotiateErrors renders error responses as JSON if the request Accept header prefers application/json
+	// over text/plain. Plain text is still the default if both are equally acceptable, like with "*/*".
+	NegotiateErrors bool
+
+	// ValidationStatusCode for missing required fields and errors from validating the request struct,
+	// like http.StatusUnprocessableEntity. Errors parsing the form are always http.StatusBadRequest.
+	// Defaults to http.StatusBadRequest.
+	ValidationStatusCode int
+}
+
 // FormHandler takes a function that is like a regular http.Handler, except it also receives a struct with values
 // parsed from http.Request.ParseForm. Any parsing errors will result in http.StatusBadRequest.
 // Uses reflection under the hood.
+// Form field names are matched case-insensitively to struct field names,
+// or can be given with a form tag, like `form:"first_name"`.
+// Fields of type time.Time are parsed as time.RFC3339, or with FormHandlerOptions.TimeLayouts.
+// Fields can be required with a form tag option, like `form:"name,required"`, and if any are missing,
+// or blank after trimming whitespace, the result is http.StatusBadRequest listing them all, like from a ValidationError.
+// See FormHandlerOptions.ValidationStatusCode for another status code for this and validation errors.
+// Fields can have a default for when the form field is absent, with a tag like `default:"20"`, decoded like a sent value.
+// A form field that is present, even if empty, doesn't get the default.
+// Nested and embedded struct fields are decoded from form fields with dotted or bracketed names,
+// like "address.city" or "address[city]" for the City field of an Address struct field.
+// Multipart forms are also parsed, and files are set on fields of type *multipart.FileHeader,
+// or []*multipart.FileHeader for multiple files.
+// The form field name for a file is given in the form tag, defaulting to the struct field name,
+// and a maximum size can be given in human-readable units, like `form:"avatar,maxsize=1MB"`.
+// Files larger than that result in http.StatusRequestEntityTooLarge, without reading the whole file.
+// If the request struct satisfies the maxSizeGiver interface, the request body is limited to that size,
+// and larger bodies result in http.StatusRequestEntityTooLarge.
+// Forms with more values than FormHandlerOptions.MaxFields result in http.StatusBadRequest.
 // If the request struct satisfies the validator interface, also use it to validate the struct.
-func FormHandler[Req any](h func(http.ResponseWriter, *http.Request, Req)) http.HandlerFunc {
+// If it satisfies the contextValidator interface instead, it's called with the request context.
+// A *ValidationError from validation is rendered with a line per field, see ValidationError,
+// and so is any other error with a FieldErrors() map[string]string method.
+// Errors are rendered as plain text by default, see FormHandlerOptions.JSONErrors and NegotiateErrors for JSON.
+// Options can be set with the options functions, see FormHandlerOptions.
+func FormHandler[Req any](h func(http.ResponseWriter, *http.Request, Req), optsFuncs ...func(opts *FormHandlerOptions)) http.HandlerFunc {
+	opts := &FormHandlerOptions{
+		MaxFields:      1000,
+		MaxMemoryBytes: 32 << 20,
+	}
+	for _, f := range optsFuncs {
+		f(opts)
+	}
+	if opts.ValidationStatusCode == 0 {
+		opts.ValidationStatusCode = http.StatusBadRequest
+	}
+
+	ft := formTypeFor(reflect.TypeOf((*Req)(nil)).Elem())
+	files, required := ft.files, ft.required
+	var maxSize int64
+	if req, ok := any(*new(Req)).(maxSizeGiver); ok {
+		maxSize = req.MaxSizeBytes()
+	}
+	decoderConfig := mapstructure.DecoderConfig{
+		DecodeHook:       stringToTimeHook(opts.TimeLayouts),
+		MatchName:        strings.EqualFold,
+		TagName:          "form",
+		WeaklyTypedInput: true,
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req Req
+
+		asJSON := opts.JSONErrors ||
+			(opts.NegotiateErrors && negotiate(r.Header.Get("Accept"), "text/plain", "application/json") == "application/json")
+
+		if maxSize > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+		}
+
 		if err := r.ParseForm(); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			if message, ok := bodyTooLargeMessage(err); ok {
+				formError(w, asJSON, http.StatusRequestEntityTooLarge, message)
+				return
+			}
+			formError(w, asJSON, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		form := map[string]any{}
-		for k := range r.Form {
-			if len(r.Form[k]) > 1 {
-				form[k] = r.Form[k]
-				continue
+		if isMultipartForm(r) {
+			if err := parseMultipartForm(r, opts.MaxMemoryBytes, files); err != nil {
+				if errors.As(err, &fileTooLargeError{}) {
+					formError(w, asJSON, http.StatusRequestEntityTooLarge, err.Error())
+					return
+				}
+				if message, ok := bodyTooLargeMessage(err); ok {
+					formError(w, asJSON, http.StatusRequestEntityTooLarge, message)
+					return
+				}
+				formError(w, asJSON, http.StatusBadRequest, err.Error())
+				return
 			}
-			form[k] = r.Form.Get(k)
 		}
-		if err := mapstructure.WeakDecode(form, &req); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+
+		values := r.Form
+		if opts.IncludeQuery {
+			values = url.Values{}
+			for k, vs := range r.URL.Query() {
+				values[k] = vs
+			}
+			for k, vs := range r.PostForm {
+				values[k] = vs
+			}
+		}
+
+		var fields int
+		for k := range values {
+			fields += len(values[k])
+		}
+		if fields > opts.MaxFields {
+			formError(w, asJSON, http.StatusBadRequest, fmt.Sprintf("too many form fields, max is %v", opts.MaxFields))
+			return
+		}
+
+		if len(required) > 0 {
+			var missing ValidationError
+			for _, f := range required {
+				if !f.present(values, r.MultipartForm) {
+					missing.Add(f.name, "is required")
+				}
+			}
+			if err := missing.Err(); err != nil {
+				formValidationError(w, asJSON, opts.ValidationStatusCode, err)
+				return
+			}
+		}
+
+		form := valuesMap(values)
+		setDefaults(form, ft.defaults)
+		nestKeys(form)
+		config := decoderConfig
+		config.Result = &req
+		dec, err := mapstructure.NewDecoder(&config)
+		if err != nil {
+			formError(w, asJSON, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err := dec.Decode(form); err != nil {
+			formError(w, asJSON, http.StatusBadRequest, err.Error())
 			return
 		}
+		setFileFields(reflect.ValueOf(&req), r.MultipartForm, files)
 
-		if req, ok := any(req).(validator); ok {
-			if err := req.Validate(); err != nil {
-				http.Error(w, fmt.Sprintf("invalid form: %v", err), http.StatusBadRequest)
+		// Only box the request in an interface if it validates, to save an allocation
+		if ft.validates {
+			if err := validate(r.Context(), req); err != nil {
+				formValidationError(w, asJSON, opts.ValidationStatusCode, err)
 				return
 			}
 		}
@@ -56,81 +232,976 @@ func FormHandler[Req any](h func(http.ResponseWriter, *http.Request, Req)) http.
 	}
 }
 
+// valuesMap for decoding with mapstructure, with a string for single values, and a []string for repeated keys.
+func valuesMap(values url.Values) map[string]any {
+	m := make(map[string]any, len(values))
+	for k := range values {
+		if len(values[k]) > 1 {
+			m[k] = values[k]
+			continue
+		}
+		m[k] = values.Get(k)
+	}
+	return m
+}
+
+// nestKeys adds nested maps to m for keys with dotted or bracketed paths, like "address.city" or "address[city]",
+// so mapstructure can decode them into nested and embedded structs. The keys themselves are kept as well,
+// so tags with dots in them still match. Keys with empty path segments, like "tags[]", are skipped,
+// as are paths that conflict with a value in m, like "address.city" when "address" is also a key.
+func nestKeys(m map[string]any) {
+	var nested []string
+	for k := range m {
+		if strings.ContainsAny(k, ".[") {
+			nested = append(nested, k)
+		}
+	}
+	// Sort for deterministic handling of conflicting paths
+	sort.Strings(nested)
+
+outer:
+	for _, k := range nested {
+		path := splitKeyPath(k)
+		if path == nil {
+			continue
+		}
+
+		current := m
+		for _, segment := range path[:len(path)-1] {
+			next, ok := current[segment]
+			if !ok {
+				next = map[string]any{}
+				current[segment] = next
+			}
+			nextMap, ok := next.(map[string]any)
+			if !ok {
+				continue outer
+			}
+			current = nextMap
+		}
+
+		last := path[len(path)-1]
+		if _, ok := current[last]; !ok {
+			current[last] = m[k]
+		}
+	}
+}
+
+// splitKeyPath like "address.city" or "address[city]" into its segments, like ["address", "city"].
+// Returns nil if any segment is empty or the brackets don't match.
+func splitKeyPath(k string) []string {
+	var path []string
+	for _, part := range strings.Split(k, ".") {
+		name, rest, hasBracket := strings.Cut(part, "[")
+		path = append(path, name)
+		for hasBracket {
+			var segment string
+			var ok bool
+			segment, rest, ok = strings.Cut(rest, "]")
+			if !ok {
+				return nil
+			}
+			path = append(path, segment)
+			if rest == "" {
+				break
+			}
+			if !strings.HasPrefix(rest, "[") {
+				return nil
+			}
+			rest = rest[1:]
+		}
+	}
+	for _, segment := range path {
+		if segment == "" {
+			return nil
+		}
+	}
+	return path
+}
+
+// formType is the analyzed request struct type for FormHandler.
+type formType struct {
+	files     []fileField
+	required  []requiredField
+	defaults  []defaultField
+	validates bool
+}
+
+// formTypes caches *formType by reflect.Type, so the reflection work is shared by handlers with the same request type.
+var formTypes sync.Map
+
+// formTypeFor t, from the cache if it's been analyzed before.
+func formTypeFor(t reflect.Type) *formType {
+	if ft, ok := formTypes.Load(t); ok {
+		return ft.(*formType)
+	}
+	ft, _ := formTypes.LoadOrStore(t, &formType{
+		files:    fileFields(t),
+		required: requiredFields(t),
+		defaults: defaultFields(t, "form"),
+		validates: t.Implements(reflect.TypeOf((*validator)(nil)).Elem()) ||
+			t.Implements(reflect.TypeOf((*contextValidator)(nil)).Elem()),
+	})
+	return ft.(*formType)
+}
+
+// formError writes an error response for FormHandler, as JSON if asJSON, otherwise as plain text.
+func formError(w http.ResponseWriter, asJSON bool, code int, message string) {
+	if !asJSON {
+		http.Error(w, message, code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	writeResponse(w, errorResponse{Error: message})
+}
+
+// formValidationError writes an error response with the status code for a FormHandler validation error, as JSON if asJSON,
+// otherwise as plain text, with a line per field if the error has field errors.
+func formValidationError(w http.ResponseWriter, asJSON bool, code int, err error) {
+	if asJSON {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(code)
+		writeResponse(w, validationErrorResponse(err))
+		return
+	}
+
+	if lines, ok := fieldErrorLines(err); ok {
+		http.Error(w, "invalid form:\n"+lines, code)
+		return
+	}
+	http.Error(w, fmt.Sprintf("invalid form: %v", err), code)
+}
+
+// requiredField is a struct field declared required with a tag like `form:"name,required"`.
+type requiredField struct {
+	name   string
+	tagged bool
+}
+
+// requiredFields of the struct type t.
+// The form field name is the first part of the form tag, and defaults to the struct field name, matched case-insensitively.
+func requiredFields(t reflect.Type) []requiredField {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []requiredField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, options, _ := strings.Cut(sf.Tag.Get("form"), ",")
+		for _, option := range strings.Split(options, ",") {
+			if option != "required" {
+				continue
+			}
+			f := requiredField{name: sf.Name}
+			if name != "" {
+				f.name = name
+				f.tagged = true
+			}
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// defaultField is a struct field with a default value, given with a tag like `default:"20"`.
+type defaultField struct {
+	name   string
+	tagged bool
+	value  string
+}
+
+// defaultFields of the struct type t, with the field name given in the tag with tagName,
+// like `form:"page_size"`, defaulting to the struct field name, matched case-insensitively.
+func defaultFields(t reflect.Type, tagName string) []defaultField {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []defaultField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		value, ok := sf.Tag.Lookup("default")
+		if !ok {
+			continue
+		}
+		f := defaultField{name: sf.Name, value: value}
+		if name, _, _ := strings.Cut(sf.Tag.Get(tagName), ","); name != "" {
+			f.name = name
+			f.tagged = true
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// setDefaults in the values map for mapstructure, for fields whose key is absent,
+// so the default is decoded like a sent value. Keys that are present keep their value, even if it's empty.
+func setDefaults(m map[string]any, fields []defaultField) {
+outer:
+	for _, f := range fields {
+		for k := range m {
+			if k == f.name || (!f.tagged && strings.EqualFold(k, f.name)) {
+				continue outer
+			}
+		}
+		m[f.name] = f.value
+	}
+}
+
+// present returns whether the field has a non-blank value in values, or a file in the multipart form.
+func (f requiredField) present(values url.Values, multipartForm *multipart.Form) bool {
+	matches := func(k string) bool {
+		return k == f.name || (!f.tagged && strings.EqualFold(k, f.name))
+	}
+
+	for k, vs := range values {
+		if !matches(k) {
+			continue
+		}
+		for _, v := range vs {
+			if strings.TrimSpace(v) != "" {
+				return true
+			}
+		}
+	}
+
+	if multipartForm != nil {
+		for k, fhs := range multipartForm.File {
+			if matches(k) && len(fhs) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FormHandlerE is like FormHandler, except the function also returns a struct and an error, like with JSONHandler.
+// The form is parsed, validated, and has errors rendered exactly like in FormHandler.
+// The returned struct and error are then rendered like in JSONHandler, so the struct is encoded as JSON,
+// and the statusCodeGiver and contentTypeGiver interfaces are respected.
+func FormHandlerE[Req any, Res any](h func(http.ResponseWriter, *http.Request, Req) (Res, error), optsFuncs ...func(opts *FormHandlerOptions)) http.HandlerFunc {
+	jsonOpts := &JSONHandlerOptions{}
+	return FormHandler(func(w http.ResponseWriter, r *http.Request, req Req) {
+		res, err := h(w, r, req)
+		writeJSONResult(w, r, jsonOpts, "", res, err)
+	}, optsFuncs...)
+}
+
+// stringToTimeHook is a mapstructure.DecodeHookFunc to parse strings into time.Time,
+// trying time.RFC3339 and then the given layouts.
+func stringToTimeHook(layouts []string) mapstructure.DecodeHookFuncType {
+	layouts = append([]string{time.RFC3339}, layouts...)
+
+	return func(from reflect.Type, to reflect.Type, data any) (any, error) {
+		if from.Kind() != reflect.String || to != reflect.TypeOf(time.Time{}) {
+			return data, nil
+		}
+
+		s := data.(string)
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("cannot parse %q as time", s)
+	}
+}
+
 // statusCodeGiver is something that can give a status code.
 type statusCodeGiver interface {
 	StatusCode() int
 }
 
+// HTTPError is an error with an HTTP status code. It satisfies statusCodeGiver.
+// Handlers like JSONHandler and ErrorHandler use Message as the error in the response body,
+// or the status text for the code if it's empty.
+// Err is an optional underlying error, which is never shown to clients, but can be unwrapped for logging.
+type HTTPError struct {
+	Code    int
+	Message string
+	Err     error
+}
+
+// NewHTTPError with the given code, wrapping err.
+func NewHTTPError(code int, err error) HTTPError {
+	return HTTPError{Code: code, Err: err}
+}
+
+// Error satisfies error, with the code, status text, message, and underlying error,
+// like "429 Too Many Requests: your quota is exhausted".
+func (e HTTPError) Error() string {
+	s := strconv.Itoa(e.Code) + " " + http.StatusText(e.Code)
+	if e.Message != "" {
+		s += ": " + e.Message
+	}
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+// Unwrap the underlying error, for errors.Is and errors.As.
+func (e HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// message for clients, which is Message or the status text for the code.
+func (e HTTPError) message() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return http.StatusText(e.Code)
+}
+
+// StatusCode satisfies statusCodeGiver.
+func (e HTTPError) StatusCode() int {
+	return e.Code
+}
+
+// NoContent is a response for JSONHandler and friends that results in http.StatusNoContent and an empty body.
+// It satisfies statusCodeGiver.
+type NoContent struct{}
+
+// StatusCode satisfies statusCodeGiver.
+func (NoContent) StatusCode() int {
+	return http.StatusNoContent
+}
+
+// ErrorHandler takes a function that is like a regular http.Handler, except it can also return an error.
+// The error is rendered with RenderError, so the format is negotiated from the Accept header,
+// and the status code is taken from the error if it satisfies the statusCodeGiver interface.
+// Nothing is written for a nil error, so the function is responsible for the successful response.
+func ErrorHandler(h func(http.ResponseWriter, *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h(w, r); err != nil {
+			RenderError(w, r, err)
+		}
+	}
+}
+
+// fieldErrorsGiver is something that can give error messages by field name, like ValidationError.
+type fieldErrorsGiver interface {
+	FieldErrors() map[string]string
+}
+
+// fieldErrorLines for an error wrapping a fieldErrorsGiver, with a "field: message" line per field.
+// A ValidationError keeps the order its fields were added in, others are sorted by field name.
+func fieldErrorLines(err error) (string, bool) {
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return strings.TrimSuffix(ve.lines(), "\n"), true
+	}
+
+	var feg fieldErrorsGiver
+	if !errors.As(err, &feg) {
+		return "", false
+	}
+	fieldErrors := feg.FieldErrors()
+	fields := make([]string, 0, len(fieldErrors))
+	for field := range fieldErrors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	lines := make([]string, 0, len(fields))
+	for _, field := range fields {
+		lines = append(lines, field+": "+fieldErrors[field])
+	}
+	return strings.Join(lines, "\n"), true
+}
+
+// contentTypeGiver is something that can give a Content-Type.
+type contentTypeGiver interface {
+	ContentType() string
+}
+
+// headerGiver is something that can give response headers.
+type headerGiver interface {
+	Headers() http.Header
+}
+
+// jsonStreamer is something that can write itself as JSON, streaming directly to the client.
+type jsonStreamer interface {
+	WriteJSON(w io.Writer) error
+}
+
 // maxSizeGiver is something that can give a max size in bytes.
 type maxSizeGiver interface {
 	MaxSizeBytes() int64
 }
 
+// JSONHandlerOptions for JSONHandler.
+type JSONHandlerOptions struct {
+	// DisallowUnknownFields in the request body, so a field that doesn't match the request struct
+	// results in http.StatusBadRequest instead of being ignored. Disabled by default.
+	DisallowUnknownFields bool
+
+	// MaxSizeBytes of the request body, for request structs that don't satisfy the maxSizeGiver interface.
+	// Larger bodies result in http.StatusRequestEntityTooLarge. Defaults to 1 MiB. Zero or negative means no limit.
+	MaxSizeBytes int64
+
+	// EncodeTimeout for encoding the response body as JSON. Zero means no timeout, which is the default.
+	// If encoding takes longer, http.StatusInternalServerError is returned.
+	// This protects against buggy or adversarial MarshalJSON implementations that hang.
+	// Note that encoding then runs in a separate goroutine, which costs a little per request, and that
+	// a goroutine can't be stopped from the outside, so it keeps running (and using resources) after the timeout.
+	// Panics during encoding in the goroutine are recovered and returned as encoding errors.
+	EncodeTimeout time.Duration
+
+	// Indent success response bodies with the given string per level, like "  ", for human-readable JSON.
+	// Empty by default, which gives compact JSON.
+	Indent string
+
+	// UseNumber when decoding the request body, so numbers in any values, like in map[string]any,
+	// are decoded as json.Number instead of float64, which loses precision for integers larger than 2^53.
+	// Disabled by default.
+	UseNumber bool
+
+	// JSONPCallbackParam enables JSONP for GET requests, when the named query parameter is present.
+	// The response body is then wrapped in a call to the callback, and the Content-Type is application/javascript.
+	// Callback names must be JavaScript identifiers, optionally separated by dots, or http.StatusBadRequest is returned.
+	// Error responses are not wrapped.
+	// JSONP bypasses the same-origin policy, so only enable it for responses that are safe to share with any site.
+	// Empty by default, which disables JSONP.
+	JSONPCallbackParam string
+
+	// RequestIDField is the name of a field in responses with the request ID from RequestIDFunc,
+	// like "RequestID". Nothing is added if the request has no ID.
+	// In success responses, the field is added last to responses that encode as a JSON object,
+	// so don't use a name that the response already has. Other and streamed responses are left as is.
+	// Empty by default, which disables the field.
+	RequestIDField string
+
+	// RequestIDFunc returns the request ID for the request context, or the empty string if there is none.
+	// Defaults to RequestIDFromContext, for use with the RequestID Middleware.
+	RequestIDFunc func(ctx context.Context) string
+
+	// ValidationStatusCode for errors from validating the request struct, like http.StatusUnprocessableEntity.
+	// Errors decoding the request body are always http.StatusBadRequest. Defaults to http.StatusBadRequest.
+	ValidationStatusCode int
+
+	// PresentFields records the top-level keys of a JSON object request body, so handlers can tell a field
+	// that was omitted from one that was sent with its zero value or null, like for PATCH requests.
+	// Get them in the function with PresentFieldsFromContext. Disabled by default, because the body is then read twice.
+	PresentFields bool
+
+	// ErrorResponse returns the value to encode as JSON in error responses, to customize their shape.
+	// It's called with the error from the handler function, or the error from decoding, validating,
+	// or encoding. The status code is chosen as usual, and RequestIDField is not used.
+	// Nil by default, which gives responses like {"Error":"..."}.
+	ErrorResponse func(err error) any
+}
+
+// limitBody of the request to the size from req if it satisfies maxSizeGiver, and otherwise to opts.MaxSizeBytes.
+func limitBody(w http.ResponseWriter, r *http.Request, opts *JSONHandlerOptions, req any) {
+	if req, ok := req.(maxSizeGiver); ok {
+		r.Body = http.MaxBytesReader(w, r.Body, req.MaxSizeBytes())
+		return
+	}
+	if opts.MaxSizeBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, opts.MaxSizeBytes)
+	}
+}
+
+// jsonpCallbackMatcher matches safe JSONP callback names, like "callback" or "app.handlers.onData".
+var jsonpCallbackMatcher = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*(\.[a-zA-Z_$][a-zA-Z0-9_$]*)*$`)
+
 // JSONHandler takes a function that is like a regular http.Handler, except it also receives a struct with values
 // parsed from the request body as JSON. The function also returns a struct that will be encoded as JSON in the response.
+// The request body is limited to 1 MiB by default, see JSONHandlerOptions.MaxSizeBytes, and larger bodies result in
+// http.StatusRequestEntityTooLarge. If the request struct satisfies the maxSizeGiver interface, its size is used instead.
+// If the request struct satisfies the validator interface, also use it to validate the struct.
+// If it satisfies the contextValidator interface instead, with a Validate(ctx context.Context) error method,
+// it's called with the request context.
+// Validation errors result in http.StatusBadRequest, and if the error wraps multiple errors, like from errors.Join,
+// each is rendered as a separate entry in the Errors field of the response.
+// A *ValidationError, from validation or the function, is rendered in the Fields field, see ValidationError.
 // If either the response struct or error satisfy the statusCodeGiver interface, the given HTTP status code is returned.
-func JSONHandler[Req any, Res any](h func(http.ResponseWriter, *http.Request, Req) (Res, error)) http.HandlerFunc {
+// If the response is a nil pointer, or the status code is http.StatusNoContent, like for NoContent, nothing is written
+// to the body.
+// The Content-Type of all responses is application/json.
+// If the response struct satisfies the contentTypeGiver interface, the given Content-Type is set for the success response
+// instead, like a vendor media type. JSONP responses are application/javascript.
+// Headers set on the http.ResponseWriter in the function are kept, because the status code is written after it returns.
+// Note that they're then also part of any error response. Alternatively, if the response struct satisfies the
+// headerGiver interface, like with a Headers() http.Header method, the given headers are set for the success response.
+// If the response struct satisfies the jsonStreamer interface, with a WriteJSON(w io.Writer) error method,
+// it's called to stream the response body directly to the client instead of encoding it to a buffer first,
+// for very large responses. The status code and headers are then written before WriteJSON is called, so an error
+// from it can't change the status code, and is only logged. EncodeTimeout and Indent don't apply to streamed responses.
+// Options can be set with the options functions, see JSONHandlerOptions.
+func JSONHandler[Req any, Res any](h func(http.ResponseWriter, *http.Request, Req) (Res, error), optsFuncs ...func(opts *JSONHandlerOptions)) http.HandlerFunc {
+	opts := &JSONHandlerOptions{
+		MaxSizeBytes: 1 << 20,
+	}
+	for _, f := range optsFuncs {
+		f(opts)
+	}
+	if opts.RequestIDFunc == nil {
+		opts.RequestIDFunc = RequestIDFromContext
+	}
+	if opts.ValidationStatusCode == 0 {
+		opts.ValidationStatusCode = http.StatusBadRequest
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req Req
 
-		if req, ok := any(req).(maxSizeGiver); ok {
-			r.Body = http.MaxBytesReader(w, r.Body, req.MaxSizeBytes())
+		var callback string
+		if opts.JSONPCallbackParam != "" && r.Method == http.MethodGet && r.URL.Query().Has(opts.JSONPCallbackParam) {
+			callback = r.URL.Query().Get(opts.JSONPCallbackParam)
+			if !jsonpCallbackMatcher.MatchString(callback) {
+				err := errors.New("invalid JSONP callback")
+				writeErrorResponse(w, r, opts, http.StatusBadRequest, err, errorResponse{Error: err.Error()})
+				return
+			}
 		}
 
-		// Try reading a request body, skip if there is none
-		br := bufio.NewReader(r.Body)
-		if _, err := br.Peek(1); err == nil {
-			dec := json.NewDecoder(br)
+		limitBody(w, r, opts, req)
 
-			if err := dec.Decode(&req); err != nil {
-				w.WriteHeader(http.StatusBadRequest)
-				writeResponse(w, errorResponse{
-					Error: fmt.Errorf("error decoding request body as JSON: %w", err).Error(),
-				})
+		present, err := decodeJSONBody(r.Body, opts, &req)
+		if err != nil {
+			if message, ok := bodyTooLargeMessage(err); ok {
+				writeErrorResponse(w, r, opts, http.StatusRequestEntityTooLarge, err, errorResponse{Error: message})
 				return
 			}
+			err = fmt.Errorf("error decoding request body as JSON: %w", err)
+			writeErrorResponse(w, r, opts, http.StatusBadRequest, err, errorResponse{Error: err.Error()})
+			return
+		}
+		if opts.PresentFields {
+			r = r.WithContext(context.WithValue(r.Context(), presentFieldsContextKey{}, present))
+		}
+
+		if err := validate(r.Context(), req); err != nil {
+			writeErrorResponse(w, r, opts, opts.ValidationStatusCode, err, validationErrorResponse(err))
+			return
 		}
 
 		res, err := h(w, r, req)
+		writeJSONResult(w, r, opts, callback, res, err)
+	}
+}
+
+// bodyTooLargeMessage for an error wrapping a *http.MaxBytesError, from a body limited with http.MaxBytesReader.
+func bodyTooLargeMessage(err error) (string, bool) {
+	var mbe *http.MaxBytesError
+	if !errors.As(err, &mbe) {
+		return "", false
+	}
+	return fmt.Sprintf("request body too large, max is %v bytes", mbe.Limit), true
+}
+
+type presentFieldsContextKey struct{}
+
+// PresentFieldsFromContext returns the top-level keys of the JSON object request body, as sent by the client,
+// if the JSONHandlerOptions.PresentFields option is set. A key sent with null is present.
+// Use it with pointer fields in the request struct for PATCH requests, to only update the fields that were sent.
+// Returns nil if the option isn't set or the body isn't a JSON object.
+func PresentFieldsFromContext(ctx context.Context) map[string]bool {
+	present, _ := ctx.Value(presentFieldsContextKey{}).(map[string]bool)
+	return present
+}
+
+// decodeJSONBody into v, if there is a body.
+// If opts.PresentFields is set, the top-level keys of a JSON object body are also returned.
+func decodeJSONBody(body io.Reader, opts *JSONHandlerOptions, v any) (map[string]bool, error) {
+	br := getBufioReader(body)
+	defer putBufioReader(br)
+
+	// Try reading a request body, skip if there is none
+	if _, err := br.Peek(1); err != nil {
+		return nil, nil
+	}
+
+	var r io.Reader = br
+	var present map[string]bool
+	if opts.PresentFields {
+		b, err := io.ReadAll(br)
 		if err != nil {
-			code := http.StatusInternalServerError
-			if err, ok := err.(statusCodeGiver); ok {
-				code = err.StatusCode()
+			return nil, err
+		}
+		// If the body isn't an object, the decoding below reports it, so ignore the error here
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(b, &fields); err == nil {
+			present = make(map[string]bool, len(fields))
+			for name := range fields {
+				present[name] = true
 			}
+		}
+		r = bytes.NewReader(b)
+	}
 
-			w.WriteHeader(code)
-			writeResponse(w, errorResponse{Error: err.Error()})
-			return
+	dec := json.NewDecoder(r)
+	if opts.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if opts.UseNumber {
+		dec.UseNumber()
+	}
+	return present, dec.Decode(v)
+}
+
+// writeJSONResult of a JSONHandler-style function, with the response encoded as JSON,
+// or the error as an error response.
+func writeJSONResult(w http.ResponseWriter, r *http.Request, opts *JSONHandlerOptions, callback string, res any, err error) {
+	if err != nil {
+		code := errorStatusCode(err)
+		logError(r, code, err)
+		writeErrorResponse(w, r, opts, code, err, errorResponseFor(err))
+		return
+	}
+
+	code := responseStatusCode(res)
+	if code == http.StatusNoContent {
+		setResponseHeaders(w, res)
+		w.WriteHeader(code)
+		return
+	}
+
+	if res, ok := res.(jsonStreamer); ok {
+		streamJSONResult(w, r, callback, code, res)
+		return
+	}
+
+	// Try encoding to a buffer first, to catch any encoding errors
+	b, err := encodeJSON(res, opts.EncodeTimeout, opts.Indent)
+	if err != nil {
+		err = fmt.Errorf("error encoding response body as JSON: %w", err)
+		writeErrorResponse(w, r, opts, http.StatusInternalServerError, err, errorResponse{Error: err.Error()})
+		return
+	}
+	// Return the buffer to the pool only after it's been copied to the client below
+	defer putBuffer(b)
+
+	if opts.RequestIDField != "" {
+		if id := opts.RequestIDFunc(r.Context()); id != "" {
+			addJSONField(b, opts.RequestIDField, id, opts.Indent)
 		}
+	}
 
-		// Try encoding to a buffer first, to catch any encoding errors
-		var b bytes.Buffer
-		if err := json.NewEncoder(&b).Encode(res); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			writeResponse(w, errorResponse{
-				Error: fmt.Errorf("error encoding response body as JSON: %w", err).Error(),
-			})
-			return
+	setJSONResultHeaders(w, callback, res)
+	if callback != "" {
+		b = wrapJSONP(callback, b)
+	}
+
+	w.WriteHeader(code)
+
+	// There's not much we can do about an error here, so ignore it
+	_, _ = io.Copy(w, b)
+}
+
+// streamJSONResult from a jsonStreamer directly to the client, without buffering.
+// The status code is written before streaming, so errors can only be logged.
+func streamJSONResult(w http.ResponseWriter, r *http.Request, callback string, code int, res jsonStreamer) {
+	setJSONResultHeaders(w, callback, res)
+	w.WriteHeader(code)
+
+	// There's not much we can do about write errors here, so ignore them
+	if callback != "" {
+		_, _ = io.WriteString(w, "/**/"+callback+"(")
+	}
+	if err := res.WriteJSON(w); err != nil {
+		logError(r, http.StatusInternalServerError, fmt.Errorf("error streaming response body as JSON: %w", err))
+	}
+	if callback != "" {
+		_, _ = io.WriteString(w, ");\n")
+	}
+}
+
+// setJSONResultHeaders for a success response, from the response and for JSONP if there's a callback.
+func setJSONResultHeaders(w http.ResponseWriter, callback string, res any) {
+	setResponseHeaders(w, res)
+	w.Header().Set("Content-Type", "application/json")
+	if res, ok := res.(contentTypeGiver); ok {
+		w.Header().Set("Content-Type", res.ContentType())
+	}
+
+	if callback != "" {
+		w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+	}
+}
+
+// setResponseHeaders from a response from a handler function, if it satisfies headerGiver and isn't a nil pointer.
+// The headers replace any headers with the same name already set on w.
+func setResponseHeaders(w http.ResponseWriter, res any) {
+	if v := reflect.ValueOf(res); v.Kind() == reflect.Pointer && v.IsNil() {
+		return
+	}
+	if res, ok := res.(headerGiver); ok {
+		for name, values := range res.Headers() {
+			w.Header()[http.CanonicalHeaderKey(name)] = values
 		}
+	}
+}
+
+// responseStatusCode for a response from a handler function.
+// A typed nil pointer results in http.StatusNoContent, and otherwise the status code is used if the response
+// satisfies statusCodeGiver. Defaults to http.StatusOK.
+func responseStatusCode(res any) int {
+	if v := reflect.ValueOf(res); v.Kind() == reflect.Pointer && v.IsNil() {
+		return http.StatusNoContent
+	}
+	if res, ok := res.(statusCodeGiver); ok {
+		return res.StatusCode()
+	}
+	return http.StatusOK
+}
 
-		code := http.StatusOK
-		if res, ok := any(res).(statusCodeGiver); ok {
-			code = res.StatusCode()
+// errorStatusCode for an error from a handler function.
+// If the error satisfies statusCodeGiver, its status code is used, and then that of a wrapped HTTPError
+// or ValidationError. Defaults to http.StatusInternalServerError.
+func errorStatusCode(err error) int {
+	if err, ok := err.(statusCodeGiver); ok {
+		return err.StatusCode()
+	}
+	if httpErr, ok := asHTTPError(err); ok {
+		return httpErr.StatusCode()
+	}
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return ve.StatusCode()
+	}
+	return http.StatusInternalServerError
+}
+
+// errorResponseFor an error from a handler function, with field messages for a wrapped ValidationError.
+func errorResponseFor(err error) errorResponse {
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return validationErrorResponse(ve)
+	}
+	return errorResponse{Error: errorMessage(err)}
+}
+
+// errorMessage for clients from an error from a handler function.
+// An HTTPError, also as a pointer or wrapped, gives its message, so Err is never shown.
+// Other errors give their Error string.
+func errorMessage(err error) string {
+	if httpErr, ok := asHTTPError(err); ok {
+		return httpErr.message()
+	}
+	return err.Error()
+}
+
+// asHTTPError finds an HTTPError or *HTTPError in the chain of err.
+func asHTTPError(err error) (HTTPError, bool) {
+	var httpErr HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr, true
+	}
+	var httpErrPtr *HTTPError
+	if errors.As(err, &httpErrPtr) && httpErrPtr != nil {
+		return *httpErrPtr, true
+	}
+	return HTTPError{}, false
+}
+
+var errEncodeTimeout = errors.New("timeout")
+
+// bufioReaders is a pool of *bufio.Reader, see getBufioReader and putBufioReader.
+var bufioReaders sync.Pool
+
+// getBufioReader from the pool, reset to read from r.
+func getBufioReader(r io.Reader) *bufio.Reader {
+	if br, ok := bufioReaders.Get().(*bufio.Reader); ok {
+		br.Reset(r)
+		return br
+	}
+	return bufio.NewReader(r)
+}
+
+// putBufioReader back in the pool, without a reference to what it read from.
+func putBufioReader(br *bufio.Reader) {
+	br.Reset(nil)
+	bufioReaders.Put(br)
+}
+
+// buffers is a pool of *bytes.Buffer, see getBuffer and putBuffer.
+var buffers sync.Pool
+
+// maxPooledBufferSize is the largest buffer capacity kept in the pool, so a single large response
+// doesn't keep a lot of memory around.
+const maxPooledBufferSize = 64 << 10
+
+// getBuffer from the pool, empty.
+func getBuffer() *bytes.Buffer {
+	if b, ok := buffers.Get().(*bytes.Buffer); ok {
+		return b
+	}
+	return &bytes.Buffer{}
+}
+
+// putBuffer back in the pool, if it's not too large.
+func putBuffer(b *bytes.Buffer) {
+	if b.Cap() > maxPooledBufferSize {
+		return
+	}
+	b.Reset()
+	buffers.Put(b)
+}
+
+// encodeJSON encodes v to a buffer from the pool, indented if indent is not empty.
+// Return the buffer with putBuffer when done with it. If timeout is positive, encoding runs in a goroutine,
+// and errEncodeTimeout is returned if it doesn't finish in time.
+func encodeJSON(v any, timeout time.Duration, indent string) (*bytes.Buffer, error) {
+	encode := func(b *bytes.Buffer) error {
+		enc := json.NewEncoder(b)
+		if indent != "" {
+			enc.SetIndent("", indent)
 		}
-		w.WriteHeader(code)
+		return enc.Encode(v)
+	}
 
-		// There's not much we can do about an error here, so ignore it
-		_, _ = io.Copy(w, &b)
+	if timeout <= 0 {
+		b := getBuffer()
+		if err := encode(b); err != nil {
+			putBuffer(b)
+			return nil, err
+		}
+		return b, nil
+	}
+
+	type result struct {
+		b   *bytes.Buffer
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		// The buffer isn't returned to the pool on panics or timeouts, because nobody knows when it's safe to do so
+		b := getBuffer()
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- result{err: fmt.Errorf("panic: %v", rec)}
+			}
+		}()
+		if err := encode(b); err != nil {
+			putBuffer(b)
+			done <- result{err: err}
+			return
+		}
+		done <- result{b: b}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-done:
+		return res.b, res.err
+	case <-timer.C:
+		return nil, errEncodeTimeout
 	}
 }
 
+// addJSONField with name and value last in b, if b holds an encoded JSON object, indented like the rest if indent is not empty.
+func addJSONField(b *bytes.Buffer, name, value, indent string) {
+	data := bytes.TrimRight(b.Bytes(), "\n")
+	if len(data) < 2 || data[0] != '{' || data[len(data)-1] != '}' {
+		return
+	}
+
+	// Errors can't happen for strings, so ignore them
+	encodedName, _ := json.Marshal(name)
+	encodedValue, _ := json.Marshal(value)
+
+	// Cut before the closing brace and any whitespace before it
+	inner := bytes.TrimRight(data[:len(data)-1], " \t\r\n")
+	b.Truncate(len(inner))
+	if len(inner) > 1 {
+		b.WriteByte(',')
+	}
+	if indent != "" {
+		b.WriteString("\n" + indent)
+	}
+	b.Write(encodedName)
+	b.WriteByte(':')
+	if indent != "" {
+		b.WriteByte(' ')
+	}
+	b.Write(encodedValue)
+	if indent != "" {
+		b.WriteByte('\n')
+	}
+	b.WriteString("}\n")
+}
+
+// wrapJSONP wraps the encoded JSON in a call to callback.
+// The leading empty comment protects against some content sniffing attacks.
+func wrapJSONP(callback string, b *bytes.Buffer) *bytes.Buffer {
+	var wrapped bytes.Buffer
+	wrapped.WriteString("/**/" + callback + "(")
+	wrapped.Write(bytes.TrimSuffix(b.Bytes(), []byte("\n")))
+	wrapped.WriteString(");\n")
+	return &wrapped
+}
+
+// writeErrorResponse with the status code, including the request ID if configured in opts.
+func writeErrorResponse(w http.ResponseWriter, r *http.Request, opts *JSONHandlerOptions, code int, err error, res errorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if opts.ErrorResponse != nil {
+		writeResponse(w, opts.ErrorResponse(err))
+		return
+	}
+
+	if opts.RequestIDField == "" {
+		writeResponse(w, res)
+		return
+	}
+	id := opts.RequestIDFunc(r.Context())
+	if id == "" {
+		writeResponse(w, res)
+		return
+	}
+
+	m := map[string]any{"Error": res.Error, opts.RequestIDField: id}
+	if len(res.Errors) > 0 {
+		m["Errors"] = res.Errors
+	}
+	if len(res.Fields) > 0 {
+		m["Fields"] = res.Fields
+	}
+	writeResponse(w, m)
+}
+
 func writeResponse(w io.Writer, v any) {
 	// If there's an error here, it's probably an error writing to the client that we can't do anything about, so ignore it.
 	_ = json.NewEncoder(w).Encode(v)
 }
 
 type errorResponse struct {
-	Error string
+	Error  string
+	Errors []string          `json:",omitempty"`
+	Fields map[string]string `json:",omitempty"`
+}
+
+// multiError is an error wrapping multiple errors, like from errors.Join.
+type multiError interface {
+	Unwrap() []error
+}
+
+// validationErrorResponse for an error from validator.Validate.
+// A fieldErrorsGiver, like ValidationError, is rendered with its messages in Fields,
+// and multiple wrapped errors are rendered as separate entries in Errors.
+func validationErrorResponse(err error) errorResponse {
+	var feg fieldErrorsGiver
+	if errors.As(err, &feg) {
+		return errorResponse{Error: "invalid request", Fields: feg.FieldErrors()}
+	}
+	if err, ok := err.(multiError); ok {
+		res := errorResponse{Error: "invalid request"}
+		for _, err := range err.Unwrap() {
+			res.Errors = append(res.Errors, err.Error())
+		}
+		return res
+	}
+	return errorResponse{Error: fmt.Sprintf("invalid request: %v", err)}
 }
 
 // Middleware is a function that takes an http.Handler and returns an http.Handler.
@@ -174,56 +1245,232 @@ type ContentSecurityPolicyOptions struct {
 	FormAction     string
 	FrameAncestors string
 	ReportTo       string
+
+	// UpgradeInsecureRequests adds the upgrade-insecure-requests directive, which has no value.
+	UpgradeInsecureRequests bool
+
+	// BlockAllMixedContent adds the block-all-mixed-content directive, which has no value.
+	// It's deprecated in favor of UpgradeInsecureRequests, but included for older browsers.
+	BlockAllMixedContent bool
+
+	// The list variants of the source directives are joined with spaces, like []string{"'self'", "data:"}.
+	// A non-nil list takes precedence over the string field, so an empty list omits the directive.
+	ChildSrcList       []string
+	ConnectSrcList     []string
+	DefaultSrcList     []string
+	FontSrcList        []string
+	FrameSrcList       []string
+	ImgSrcList         []string
+	ManifestSrcList    []string
+	MediaSrcList       []string
+	ObjectSrcList      []string
+	ScriptSrcList      []string
+	ScriptSrcElemList  []string
+	ScriptSrcAttrList  []string
+	StyleSrcList       []string
+	StyleSrcElemList   []string
+	StyleSrcAttrList   []string
+	WorkerSrcList      []string
+	BaseURIList        []string
+	FormActionList     []string
+	FrameAncestorsList []string
+
+	// ReportOnly sets the Content-Security-Policy-Report-Only header instead of Content-Security-Policy,
+	// so violations are reported, to the ReportTo endpoint, but not enforced.
+	// Useful for trying out a stricter policy.
+	ReportOnly bool
+
+	// Nonce generates a random nonce per request, which is added as 'nonce-<value>' to the script and style directives
+	// that are set, so inline scripts and styles with a matching nonce attribute are allowed.
+	// Get the nonce for templates with CSPNonceFromContext.
+	Nonce bool
+
+	// Sources are named source lists, like "cdn": "https://cdn.example.com https://static.example.com",
+	// which can be referenced in the directive values above with CSPRef, together with other sources.
+	// This keeps directives that share sources consistent.
+	Sources map[string]string
+}
+
+// CSPRef returns a reference to a named source list in ContentSecurityPolicyOptions.Sources,
+// to use in directive values, like opts.ScriptSrc = "'self' " + httph.CSPRef("cdn").
+// References are expanded when building the header.
+func CSPRef(name string) string {
+	return "$" + name
+}
+
+// expandSources replaces references to named source lists in the directive values with the sources.
+func (o *ContentSecurityPolicyOptions) expandSources() error {
+	for _, v := range []*string{&o.ChildSrc, &o.ConnectSrc, &o.DefaultSrc, &o.FontSrc, &o.FrameSrc, &o.ImgSrc,
+		&o.ManifestSrc, &o.MediaSrc, &o.ObjectSrc, &o.ScriptSrc, &o.ScriptSrcElem, &o.ScriptSrcAttr, &o.StyleSrc,
+		&o.StyleSrcElem, &o.StyleSrcAttr, &o.WorkerSrc, &o.BaseURI, &o.Sandbox, &o.FormAction, &o.FrameAncestors,
+		&o.ReportTo} {
+		if !strings.Contains(*v, "$") {
+			continue
+		}
+
+		tokens := strings.Fields(*v)
+		for i, token := range tokens {
+			name, ok := strings.CutPrefix(token, "$")
+			if !ok {
+				continue
+			}
+			sources, ok := o.Sources[name]
+			if !ok {
+				return fmt.Errorf("unknown CSP source list %v", name)
+			}
+			tokens[i] = sources
+		}
+		*v = strings.Join(tokens, " ")
+	}
+	return nil
 }
 
 // ContentSecurityPolicy is Middleware to set CSP headers.
 // By default this is a strict policy, disallowing everything but images, styles, scripts, and fonts from 'self'.
 // See https://developer.mozilla.org/en-US/docs/Web/HTTP/CSP
 // See https://infosec.mozilla.org/guidelines/web_security#content-security-policy
+// The options function is called once, when creating the Middleware, and the header value is built then too,
+// unless the Nonce option is set, which needs a new header value per request.
+// Panics if a directive references an unknown named source list, see CSPRef.
 func ContentSecurityPolicy(optsFunc func(opts *ContentSecurityPolicyOptions)) Middleware {
+	opts := newContentSecurityPolicyOptions(optsFunc)
+	if err := opts.expandSources(); err != nil {
+		panic(err.Error())
+	}
+
+	header := "Content-Security-Policy"
+	if opts.ReportOnly {
+		header = "Content-Security-Policy-Report-Only"
+	}
+
+	// Without a nonce, the policy is the same for every request, so build it just once
+	var policy string
+	if !opts.Nonce {
+		policy = opts.policy()
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			opts := &ContentSecurityPolicyOptions{
-				DefaultSrc: "'none'",
-				FontSrc:    "'self'",
-				ImgSrc:     "'self'",
-				ScriptSrc:  "'self'",
-				StyleSrc:   "'self'",
-			}
-
-			if optsFunc != nil {
-				optsFunc(opts)
-			}
-
-			var v string
-			v += maybeAddDirective("default-src", opts.DefaultSrc)
-			v += maybeAddDirective("child-src", opts.ChildSrc)
-			v += maybeAddDirective("connect-src", opts.ConnectSrc)
-			v += maybeAddDirective("font-src", opts.FontSrc)
-			v += maybeAddDirective("frame-src", opts.FrameSrc)
-			v += maybeAddDirective("img-src", opts.ImgSrc)
-			v += maybeAddDirective("manifest-src", opts.ManifestSrc)
-			v += maybeAddDirective("media-src", opts.MediaSrc)
-			v += maybeAddDirective("object-src", opts.ObjectSrc)
-			v += maybeAddDirective("script-src", opts.ScriptSrc)
-			v += maybeAddDirective("script-src-elem", opts.ScriptSrcElem)
-			v += maybeAddDirective("script-src-attr", opts.ScriptSrcAttr)
-			v += maybeAddDirective("style-src", opts.StyleSrc)
-			v += maybeAddDirective("style-src-elem", opts.StyleSrcElem)
-			v += maybeAddDirective("style-src-attr", opts.StyleSrcAttr)
-			v += maybeAddDirective("worker-src", opts.WorkerSrc)
-			v += maybeAddDirective("base-uri", opts.BaseURI)
-			v += maybeAddDirective("sandbox", opts.Sandbox)
-			v += maybeAddDirective("form-action", opts.FormAction)
-			v += maybeAddDirective("frame-ancestors", opts.FrameAncestors)
-			v += maybeAddDirective("report-to", opts.ReportTo)
-
-			w.Header().Set("Content-Security-Policy", strings.TrimSuffix(strings.TrimSpace(v), ";"))
+			if !opts.Nonce {
+				w.Header().Set(header, policy)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			nonce := newCSPNonce()
+			nonceOpts := *opts
+			for _, v := range []*string{&nonceOpts.ScriptSrc, &nonceOpts.ScriptSrcElem, &nonceOpts.StyleSrc, &nonceOpts.StyleSrcElem} {
+				switch *v {
+				case "":
+				case "'none'":
+					*v = "'nonce-" + nonce + "'"
+				default:
+					*v += " 'nonce-" + nonce + "'"
+				}
+			}
+			r = r.WithContext(context.WithValue(r.Context(), cspNonceContextKey{}, nonce))
+
+			w.Header().Set(header, nonceOpts.policy())
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
+// policy header value for the options.
+func (o *ContentSecurityPolicyOptions) policy() string {
+	var v string
+	v += maybeAddDirective("default-src", o.DefaultSrc)
+	v += maybeAddDirective("child-src", o.ChildSrc)
+	v += maybeAddDirective("connect-src", o.ConnectSrc)
+	v += maybeAddDirective("font-src", o.FontSrc)
+	v += maybeAddDirective("frame-src", o.FrameSrc)
+	v += maybeAddDirective("img-src", o.ImgSrc)
+	v += maybeAddDirective("manifest-src", o.ManifestSrc)
+	v += maybeAddDirective("media-src", o.MediaSrc)
+	v += maybeAddDirective("object-src", o.ObjectSrc)
+	v += maybeAddDirective("script-src", o.ScriptSrc)
+	v += maybeAddDirective("script-src-elem", o.ScriptSrcElem)
+	v += maybeAddDirective("script-src-attr", o.ScriptSrcAttr)
+	v += maybeAddDirective("style-src", o.StyleSrc)
+	v += maybeAddDirective("style-src-elem", o.StyleSrcElem)
+	v += maybeAddDirective("style-src-attr", o.StyleSrcAttr)
+	v += maybeAddDirective("worker-src", o.WorkerSrc)
+	v += maybeAddDirective("base-uri", o.BaseURI)
+	v += maybeAddDirective("sandbox", o.Sandbox)
+	v += maybeAddDirective("form-action", o.FormAction)
+	v += maybeAddDirective("frame-ancestors", o.FrameAncestors)
+	if o.UpgradeInsecureRequests {
+		v += "upgrade-insecure-requests; "
+	}
+	if o.BlockAllMixedContent {
+		v += "block-all-mixed-content; "
+	}
+	v += maybeAddDirective("report-to", o.ReportTo)
+	return strings.TrimSuffix(strings.TrimSpace(v), ";")
+}
+
+type cspNonceContextKey struct{}
+
+// CSPNonceFromContext returns the nonce set by ContentSecurityPolicy with the Nonce option,
+// or the empty string if there is none. Use it in templates like <script nonce="{{.Nonce}}">.
+func CSPNonceFromContext(ctx context.Context) string {
+	nonce, _ := ctx.Value(cspNonceContextKey{}).(string)
+	return nonce
+}
+
+func newCSPNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func newContentSecurityPolicyOptions(optsFunc func(opts *ContentSecurityPolicyOptions)) *ContentSecurityPolicyOptions {
+	opts := &ContentSecurityPolicyOptions{
+		DefaultSrc: "'none'",
+		FontSrc:    "'self'",
+		ImgSrc:     "'self'",
+		ScriptSrc:  "'self'",
+		StyleSrc:   "'self'",
+	}
+
+	if optsFunc != nil {
+		optsFunc(opts)
+	}
+
+	for _, d := range []struct {
+		list  []string
+		value *string
+	}{
+		{opts.ChildSrcList, &opts.ChildSrc},
+		{opts.ConnectSrcList, &opts.ConnectSrc},
+		{opts.DefaultSrcList, &opts.DefaultSrc},
+		{opts.FontSrcList, &opts.FontSrc},
+		{opts.FrameSrcList, &opts.FrameSrc},
+		{opts.ImgSrcList, &opts.ImgSrc},
+		{opts.ManifestSrcList, &opts.ManifestSrc},
+		{opts.MediaSrcList, &opts.MediaSrc},
+		{opts.ObjectSrcList, &opts.ObjectSrc},
+		{opts.ScriptSrcList, &opts.ScriptSrc},
+		{opts.ScriptSrcElemList, &opts.ScriptSrcElem},
+		{opts.ScriptSrcAttrList, &opts.ScriptSrcAttr},
+		{opts.StyleSrcList, &opts.StyleSrc},
+		{opts.StyleSrcElemList, &opts.StyleSrcElem},
+		{opts.StyleSrcAttrList, &opts.StyleSrcAttr},
+		{opts.WorkerSrcList, &opts.WorkerSrc},
+		{opts.BaseURIList, &opts.BaseURI},
+		{opts.FormActionList, &opts.FormAction},
+		{opts.FrameAncestorsList, &opts.FrameAncestors},
+	} {
+		if d.list != nil {
+			*d.value = strings.Join(d.list, " ")
+		}
+	}
+
+	return opts
+}
+
 func maybeAddDirective(name, value string) string {
 	if value == "" {
 		return ""
@@ -235,51 +1482,161 @@ func maybeAddDirective(name, value string) string {
 var goGetFS embed.FS
 
 type GoGetOptions struct {
-	Domain    string   // Domain to serve URLs for, for example: "maragu.dev"
-	Modules   []string // Lit of module names, for example: "httph", "foo"
-	URLPrefix string   // URL prefix to serve the module from, for example: "https://github.com/maragudk"
+	Domain        string        // Domain to serve URLs for, for example: "maragu.dev"
+	Modules       []string      // Lit of module names, for example: "httph", "foo", or patterns like "tools/*", see path.Match
+	ModuleConfigs []GoGetModule // List of modules with their own URL prefix and VCS, in addition to Modules
+	URLPrefix     string        // URL prefix to serve the module from, for example: "https://github.com/maragudk"
+	VCS           string        // VCS of the module repositories, one of "git", "hg", "svn", "bzr", or "fossil". Defaults to "git".
+
+	// DirTemplate and FileTemplate for the go-source meta tag, which gives source links on pkg.go.dev.
+	// They're appended to the module URL, for example: "/-/tree/main{/dir}" and "/-/blob/main{/dir}/{file}#L{line}".
+	// See https://github.com/golang/gddo/wiki/Source-Code-Links for the placeholders.
+	// Default to the GitHub layout for URL prefixes on github.com, and are empty otherwise, which omits the tag.
+	DirTemplate  string
+	FileTemplate string
+
+	// CheckHost restricts handling to requests for the host of Domain, or one of Hosts if set.
+	// Requests for other hosts, like unrelated sites sharing the handler, are passed through to the next handler.
+	// Hosts are compared without port and case-insensitively.
+	CheckHost bool
+	Hosts     []string
+
+	// StrictModules responds with 404 Not Found to requests for unknown modules, instead of passing them through
+	// to the next handler, for dedicated vanity servers. A request is for a module if it's a go get request
+	// for a path other than the root, or if the first path segment looks like a module name,
+	// with only ASCII letters, digits, "-", "_", and "~". Other requests, like for "/" or "/favicon.ico", still pass through.
+	StrictModules bool
+
+	// Template to use instead of the embedded one, for example for a styled landing page.
+	// It's executed with GoGetData, and must include the go-import meta tag for go get to work.
+	// Panics at construction if the template can't be executed or produces no output.
+	Template *template.Template
+}
+
+// GoGetData is the data the GoGet template is executed with.
+type GoGetData struct {
+	Domain       string
+	Module       string
+	URLPrefix    string
+	VCS          string
+	DirTemplate  string
+	FileTemplate string
+}
+
+// GoGetModule is a module for GoGetOptions.ModuleConfigs.
+type GoGetModule struct {
+	Name      string // Module name or pattern, like in GoGetOptions.Modules
+	URLPrefix string // URL prefix to serve the module from. Defaults to GoGetOptions.URLPrefix.
+	VCS       string // VCS of the module repository. Defaults to GoGetOptions.VCS.
+
+	// DirTemplate and FileTemplate like in GoGetOptions. Default to GoGetOptions.DirTemplate and FileTemplate.
+	DirTemplate  string
+	FileTemplate string
 }
 
 // GoGet is Middleware to support redirecting go get requests to module VCS URLs, popularly known as vanity URLs.
+// Modules can be patterns, like "tools/*", which match the same number of path segments of the request path.
+// Exact module names take precedence over patterns, and the requested module name is used in the response.
+// Requests for packages in a module, like "/httph/internal/foo", are served the same as for the module root,
+// so the go-import meta tag always has the module root as the import prefix.
 // See https://www.maragu.dev/blog/til-http-middleware-for-custom-go-module-paths
 func GoGet(opts GoGetOptions) Middleware {
 	if opts.Domain == "" {
 		panic("invalid domain")
 	}
 
-	if len(opts.Modules) == 0 {
+	if len(opts.Modules) == 0 && len(opts.ModuleConfigs) == 0 {
 		panic("no modules")
 	}
+
+	if opts.VCS == "" {
+		opts.VCS = "git"
+	}
+
+	configs := opts.ModuleConfigs
 	for _, m := range opts.Modules {
-		if m == "" {
+		configs = append(configs, GoGetModule{Name: m})
+	}
+
+	modules := map[string]GoGetModule{}
+	var patterns []GoGetModule
+	for _, m := range configs {
+		if m.Name == "" {
 			panic("invalid module")
 		}
-	}
+		if m.URLPrefix == "" {
+			m.URLPrefix = opts.URLPrefix
+		}
+		if !strings.HasPrefix(m.URLPrefix, "http") {
+			panic("invalid URL prefix")
+		}
+		m.URLPrefix = strings.TrimSuffix(m.URLPrefix, "/")
+		if m.VCS == "" {
+			m.VCS = opts.VCS
+		}
+		switch m.VCS {
+		case "git", "hg", "svn", "bzr", "fossil":
+		default:
+			panic("invalid VCS " + m.VCS)
+		}
+		if m.DirTemplate == "" && m.FileTemplate == "" {
+			m.DirTemplate, m.FileTemplate = opts.DirTemplate, opts.FileTemplate
+		}
+		if m.DirTemplate == "" && m.FileTemplate == "" && strings.HasPrefix(m.URLPrefix, "https://github.com/") {
+			m.DirTemplate, m.FileTemplate = "/tree/main{/dir}", "/blob/main{/dir}/{file}#L{line}"
+		}
+		if (m.DirTemplate == "") != (m.FileTemplate == "") {
+			panic("DirTemplate and FileTemplate must be set together")
+		}
 
-	if !strings.HasPrefix(opts.URLPrefix, "http") {
-		panic("invalid URL prefix")
+		if !strings.ContainsAny(m.Name, `*?[\`) {
+			if _, ok := modules[m.Name]; !ok {
+				modules[m.Name] = m
+			}
+			continue
+		}
+		if _, err := path.Match(m.Name, ""); err != nil {
+			panic("invalid module pattern " + m.Name)
+		}
+		patterns = append(patterns, m)
 	}
-	opts.URLPrefix = strings.TrimSuffix(opts.URLPrefix, "/")
-
-	t := template.Must(template.ParseFS(goGetFS, "goget.gohtml"))
 
-	modules := map[string]struct{}{}
-	for _, m := range opts.Modules {
-		modules[m] = struct{}{}
+	hosts := map[string]bool{}
+	if opts.CheckHost {
+		for _, h := range opts.Hosts {
+			hosts[stripPort(strings.ToLower(h))] = true
+		}
+		if len(hosts) == 0 {
+			domainHost, _, _ := strings.Cut(opts.Domain, "/")
+			hosts[stripPort(strings.ToLower(domainHost))] = true
+		}
 	}
 
-	type Data struct {
-		Domain    string
-		Module    string
-		URLPrefix string
+	t := opts.Template
+	if t == nil {
+		t = template.Must(template.ParseFS(goGetFS, "goget.gohtml"))
+	} else {
+		var b strings.Builder
+		data := GoGetData{Domain: opts.Domain, Module: configs[0].Name, URLPrefix: opts.URLPrefix, VCS: opts.VCS}
+		if err := t.Execute(&b, data); err != nil || strings.TrimSpace(b.String()) == "" {
+			panic("invalid template")
+		}
 	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			parts := strings.Split(r.URL.Path, "/")
-			module := parts[1]
+			if opts.CheckHost && !hosts[HostWithoutPort(r)] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			module, m := matchModule(r.URL.Path, modules, patterns)
 			// Exit early if the module is not in the list of modules
-			if _, ok := modules[module]; !ok {
+			if module == "" {
+				if opts.StrictModules && isModuleRequest(r) {
+					http.NotFound(w, r)
+					return
+				}
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -287,14 +1644,17 @@ func GoGet(opts GoGetOptions) Middleware {
 			// Redirect to the module's URL if the request is not a go-get request
 			goGet := r.URL.Query().Get("go-get")
 			if goGet != "1" {
-				http.Redirect(w, r, fmt.Sprintf("%v/%v", opts.URLPrefix, module), http.StatusPermanentRedirect)
+				http.Redirect(w, r, fmt.Sprintf("%v/%v", m.URLPrefix, module), http.StatusPermanentRedirect)
 				return
 			}
 
-			data := Data{
-				Domain:    opts.Domain,
-				Module:    module,
-				URLPrefix: opts.URLPrefix,
+			data := GoGetData{
+				Domain:       opts.Domain,
+				Module:       module,
+				URLPrefix:    m.URLPrefix,
+				VCS:          m.VCS,
+				DirTemplate:  m.DirTemplate,
+				FileTemplate: m.FileTemplate,
 			}
 			if err := t.Execute(w, data); err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -304,18 +1664,180 @@ func GoGet(opts GoGetOptions) Middleware {
 	}
 }
 
+// matchModule returns the module name for the request path, from the leading path segments that match
+// one of the exact modules, or else one of the patterns, and the matching module.
+// Returns the empty string if there is no match.
+func matchModule(p string, modules map[string]GoGetModule, patterns []GoGetModule) (string, GoGetModule) {
+	parts := strings.Split(strings.TrimPrefix(p, "/"), "/")
+
+	// Prefer the longest exact match
+	for i := len(parts); i > 0; i-- {
+		candidate := strings.Join(parts[:i], "/")
+		if m, ok := modules[candidate]; ok {
+			return candidate, m
+		}
+	}
+
+	for _, m := range patterns {
+		n := strings.Count(m.Name, "/") + 1
+		if n > len(parts) {
+			continue
+		}
+		candidate := strings.Join(parts[:n], "/")
+		// Empty segments are never modules, even if a pattern matches them
+		if ok, _ := path.Match(m.Name, candidate); ok && !strings.Contains("/"+candidate+"/", "//") {
+			return candidate, m
+		}
+	}
+	return "", GoGetModule{}
+}
+
+// isModuleRequest if it's a go get request for a path other than the root,
+// or the first path segment looks like a module name.
+func isModuleRequest(r *http.Request) bool {
+	first, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if first == "" {
+		return false
+	}
+	if r.URL.Query().Get("go-get") == "1" {
+		return true
+	}
+	for _, c := range first {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '~') {
+			return false
+		}
+	}
+	return true
+}
+
 // versionedAssetMatcher matches versioned assets like "app.abc123.js".
 // See https://regex101.com/r/bGfflm/latest
 var versionedAssetMatcher = regexp.MustCompile(`^(?P<name>[^.]+)\.[a-z0-9]+(?P<extension>\.[a-z0-9]+)$`)
 
+// hashedAssetMatcher matches the versioned assets from versionedAssetMatcher with a version that looks like a hash,
+// like "app.1a2b3c4d.js", so names like "app.min.js" aren't cached as immutable.
+var hashedAssetMatcher = regexp.MustCompile(`^[^.]+\.[a-f0-9]{8,}\.[a-z0-9]+$`)
+
+// VersionedAssetsOptions for VersionedAssetsWithOptions.
+type VersionedAssetsOptions struct {
+	// MaxAge in the Cache-Control header for versioned assets. Defaults to one year.
+	MaxAge time.Duration
+
+	// DisableImmutable leaves out the immutable directive in the Cache-Control header.
+	DisableImmutable bool
+
+	// Matcher for versioned asset paths, with named groups "name" and "extension", which are joined to get the path
+	// without the version. All matching paths get the Cache-Control header.
+	// Defaults to matching paths like "/app.abc123.js", where only versions that look like a hash,
+	// with at least 8 hex characters like "/app.1a2b3c4d.js", get the Cache-Control header.
+	Matcher *regexp.Regexp
+}
+
 // VersionedAssets is Middleware to help serve versioned assets without the version.
 // It basically strips the version from the asset path and forwards the request, probably to a static file handler.
+// Successful responses for versioned assets with a hash version, like "/app.1a2b3c4d.js", are cached for a year,
+// with "Cache-Control: public, max-age=31536000, immutable".
+// See VersionedAssetsWithOptions to configure caching.
 func VersionedAssets(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if versionedAssetMatcher.MatchString(r.URL.Path) {
-			r.URL.Path = versionedAssetMatcher.ReplaceAllString(r.URL.Path, `$1$2`)
+	return VersionedAssetsWithOptions(VersionedAssetsOptions{})(next)
+}
+
+// VersionedAssetsWithOptions is like VersionedAssets, with options.
+func VersionedAssetsWithOptions(opts VersionedAssetsOptions) Middleware {
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = 365 * 24 * time.Hour
+	}
+	cacheable := func(string) bool { return true }
+	if opts.Matcher == nil {
+		opts.Matcher = versionedAssetMatcher
+		cacheable = hashedAssetMatcher.MatchString
+	}
+	if opts.Matcher.SubexpIndex("name") < 0 || opts.Matcher.SubexpIndex("extension") < 0 {
+		panic("versioned asset matcher must have name and extension groups")
+	}
+	cacheControl := fmt.Sprintf("public, max-age=%d", int64(opts.MaxAge.Seconds()))
+	if !opts.DisableImmutable {
+		cacheControl += ", immutable"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !opts.Matcher.MatchString(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cache := cacheable(r.URL.Path)
+			r.URL.Path = opts.Matcher.ReplaceAllString(r.URL.Path, `${name}${extension}`)
+			if !cache {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			vw := &versionedAssetWriter{ResponseWriter: w, cacheControl: cacheControl}
+			next.ServeHTTP(vw, r)
+			// Nothing written means an empty http.StatusOK response
+			if !vw.wroteHeader {
+				vw.WriteHeader(http.StatusOK)
+			}
+		})
+	}
+}
+
+// VersionedPath returns the versioned path for the file with name in fsys, for the default VersionedAssets matcher,
+// like "js/app.js" to "js/app.1a2b3c4d5e6f.js", with the version from a SHA-256 hash of the file content.
+// This is the path VersionedAssets strips the version from, so use it to link to assets in templates,
+// and the link changes when the content does.
+// Returns an error if the file can't be read, or if the name can't be versioned, like "app.min.js".
+func VersionedPath(fsys fs.FS, name string) (string, error) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", fmt.Errorf("error reading %v: %w", name, err)
+	}
+	hash := sha256.Sum256(b)
+
+	ext := path.Ext(name)
+	versioned := strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(hash[:6]) + ext
+	if !versionedAssetMatcher.MatchString(versioned) {
+		return "", fmt.Errorf("cannot version %v", name)
+	}
+	return versioned, nil
+}
+
+// versionedAssetWriter sets the Cache-Control header for successful responses only,
+// so errors like http.StatusNotFound aren't cached for long.
+type versionedAssetWriter struct {
+	http.ResponseWriter
+	cacheControl string
+	wroteHeader  bool
+}
+
+func (w *versionedAssetWriter) WriteHeader(code int) {
+	if !w.wroteHeader && code >= 200 {
+		w.wroteHeader = true
+		if code < 300 || code == http.StatusNotModified {
+			w.Header().Set("Cache-Control", w.cacheControl)
 		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
 
-		next.ServeHTTP(w, r)
-	})
+func (w *versionedAssetWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush satisfies http.Flusher.
+func (w *versionedAssetWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap the http.ResponseWriter for http.ResponseController.
+func (w *versionedAssetWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
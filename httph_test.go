@@ -1,15 +1,24 @@
 package httph_test
 
 import (
+	"bytes"
+	"context"
 	_ "embed"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"html/template"
 	"io"
+	"io/fs"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"regexp"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/maragudk/is"
 
@@ -22,6 +31,43 @@ func (r validatedFormReq) Validate() error {
 	return errors.New("invalid")
 }
 
+type fieldErrorsFormReq struct{}
+
+func (r fieldErrorsFormReq) Validate() error {
+	return fieldErrors{"name": "is required", "age": "must be a number"}
+}
+
+type fieldErrors map[string]string
+
+func (f fieldErrors) Error() string {
+	return "invalid fields"
+}
+
+func (f fieldErrors) FieldErrors() map[string]string {
+	return f
+}
+
+type contextValidatedReq struct {
+	Name string
+}
+
+type contextKey struct{}
+
+func (c contextValidatedReq) Validate(ctx context.Context) error {
+	if taken, _ := ctx.Value(contextKey{}).(string); taken == c.Name {
+		return errors.New("name is taken")
+	}
+	return nil
+}
+
+type tinyFormReq struct {
+	Name string
+}
+
+func (t tinyFormReq) MaxSizeBytes() int64 {
+	return 1
+}
+
 func TestFormHandler(t *testing.T) {
 	t.Run("parses a form into a struct", func(t *testing.T) {
 		type formReq struct {
@@ -55,240 +101,1846 @@ func TestFormHandler(t *testing.T) {
 		is.Equal(t, http.StatusFound, res.Result().StatusCode)
 	})
 
-	t.Run("returns bad request on bad input values", func(t *testing.T) {
+	t.Run("parses forms with the same request type in several handlers and requests", func(t *testing.T) {
 		type formReq struct {
-			Age int
+			Name string `form:"name,required"`
+			Age  int
 		}
 
-		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req formReq) {})
+		var names []string
+		var ages []int
+		newHandler := func() http.HandlerFunc {
+			return httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req formReq) {
+				names = append(names, req.Name)
+				ages = append(ages, req.Age)
+			})
+		}
+		h1, h2 := newHandler(), newHandler()
+
+		for i, h := range []http.HandlerFunc{h1, h2, h1} {
+			vs := url.Values{}
+			vs.Set("name", fmt.Sprint("Me", i))
+			vs.Set("age", fmt.Sprint(20+i))
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, createFormRequest(vs))
+			is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		}
 
-		vs := url.Values{}
-		vs.Set("age", "not a number")
-		req := createFormRequest(vs)
 		res := httptest.NewRecorder()
+		h2.ServeHTTP(res, createFormRequest(url.Values{"age": {"1"}}))
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
 
-		h.ServeHTTP(res, req)
+		is.Equal(t, "Me0,Me1,Me2", strings.Join(names, ","))
+		is.Equal(t, 3, len(ages))
+		is.Equal(t, 22, ages[2])
+	})
 
-		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
-		is.True(t, strings.Contains(readBody(t, res), "cannot parse 'Age' as int"))
+	t.Run("applies defaults for absent form fields only", func(t *testing.T) {
+		type formReq struct {
+			Name     string   `default:"Anonymous"`
+			PageSize int      `form:"page_size" default:"20"`
+			Tags     []string `default:"new"`
+		}
+
+		var req formReq
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, fr formReq) {
+			req = fr
+		})
+
+		h.ServeHTTP(httptest.NewRecorder(), createFormRequest(url.Values{}))
+		is.Equal(t, "Anonymous", req.Name)
+		is.Equal(t, 20, req.PageSize)
+		is.Equal(t, 1, len(req.Tags))
+		is.Equal(t, "new", req.Tags[0])
+
+		h.ServeHTTP(httptest.NewRecorder(), createFormRequest(url.Values{"name": {"Me"}, "page_size": {"0"}, "tags": {"a", "b"}}))
+		is.Equal(t, "Me", req.Name)
+		is.Equal(t, 0, req.PageSize)
+		is.Equal(t, 2, len(req.Tags))
 	})
 
-	t.Run("returns bad request when Validate() returns error", func(t *testing.T) {
-		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req validatedFormReq) {})
+	t.Run("maps form fields by form tag and by field name", func(t *testing.T) {
+		type formReq struct {
+			FirstName string `form:"first_name"`
+			LastName  string
+			Age       int `form:"age_in_years"`
+		}
+
+		var got formReq
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req formReq) {
+			got = req
+		})
 
 		vs := url.Values{}
-		vs.Set("name", "")
-		req := createFormRequest(vs)
+		vs.Set("first_name", "Me")
+		vs.Set("lastname", "Myself")
+		vs.Set("age_in_years", "20")
 		res := httptest.NewRecorder()
 
-		h.ServeHTTP(res, req)
+		h.ServeHTTP(res, createFormRequest(vs))
 
-		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
-		is.Equal(t, "invalid form: invalid", readBody(t, res))
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, formReq{FirstName: "Me", LastName: "Myself", Age: 20}, got)
 	})
-}
 
-func ExampleFormHandler() {
-	type Req struct {
-		Name string
-		Age  int
-	}
+	t.Run("parses time fields with RFC3339 and the given layouts", func(t *testing.T) {
+		type formReq struct {
+			Birthdate time.Time
+			CreatedAt time.Time
+		}
 
-	h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req Req) {
-		_, _ = fmt.Fprintf(w, "Hello %v, you are %v years old", req.Name, req.Age)
+		var got formReq
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req formReq) {
+			got = req
+		}, func(opts *httph.FormHandlerOptions) {
+			opts.TimeLayouts = []string{"2006-01-02"}
+		})
+
+		vs := url.Values{}
+		vs.Set("birthdate", "2000-01-02")
+		vs.Set("createdat", "2024-03-04T05:06:07Z")
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, createFormRequest(vs))
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.True(t, got.Birthdate.Equal(time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)))
+		is.True(t, got.CreatedAt.Equal(time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)))
 	})
 
-	w := httptest.NewRecorder()
-	vs := url.Values{
-		"name": {"World"},
-		"age":  {"20"},
-	}
-	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(vs.Encode()))
-	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	h.ServeHTTP(w, r)
+	t.Run("returns bad request naming the field on invalid time values", func(t *testing.T) {
+		type formReq struct {
+			Birthdate time.Time
+		}
 
-	body, _ := io.ReadAll(w.Result().Body)
-	fmt.Println(string(body))
-	//Output: Hello World, you are 20 years old
-}
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req formReq) {})
 
-func createFormRequest(vs url.Values) *http.Request {
-	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(vs.Encode()))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	return req
-}
+		vs := url.Values{}
+		vs.Set("birthdate", "2000-01-02")
+		res := httptest.NewRecorder()
 
-func readBody(t *testing.T, r *httptest.ResponseRecorder) string {
-	t.Helper()
+		h.ServeHTTP(res, createFormRequest(vs))
 
-	d, err := io.ReadAll(r.Result().Body)
-	if err != nil {
-		t.Fatal(err)
-	}
-	return strings.TrimSpace(string(d))
-}
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		body := readBody(t, res)
+		is.True(t, strings.Contains(body, "Birthdate"))
+		is.True(t, strings.Contains(body, `cannot parse "2000-01-02" as time`))
+	})
 
-type httpError struct {
-	code int
-}
+	t.Run("returns bad request when a required field is missing", func(t *testing.T) {
+		type formReq struct {
+			Name  string `form:"name,required"`
+			Email string `form:",required"`
+			Age   int
+		}
 
-func (h *httpError) Error() string {
-	return http.StatusText(h.code)
-}
+		var called bool
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req formReq) {
+			called = true
+		})
 
-func (h *httpError) StatusCode() int {
-	return h.code
-}
+		vs := url.Values{}
+		vs.Set("name", "Me")
+		vs.Set("email", "  ")
+		res := httptest.NewRecorder()
 
-type jsonRes struct {
-	Message string
-}
+		h.ServeHTTP(res, createFormRequest(vs))
 
-func (j jsonRes) StatusCode() int {
-	return http.StatusAccepted
-}
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, "invalid form:\nEmail: is required", readBody(t, res))
+		is.True(t, !called)
 
-type tinyJSONReq struct {
-	Name string
-}
+		vs.Set("Email", "me@example.com")
+		res = httptest.NewRecorder()
 
-func (t tinyJSONReq) MaxSizeBytes() int64 {
-	return 1
-}
+		h.ServeHTTP(res, createFormRequest(vs))
 
-func TestJSONHandler(t *testing.T) {
-	t.Run("encodes response body to JSON", func(t *testing.T) {
-		type jsonRes struct {
-			Message string `json:"message"`
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.True(t, called)
+	})
+
+	t.Run("lists all missing required fields", func(t *testing.T) {
+		type formReq struct {
+			FirstName string `form:"first_name,required"`
+			LastName  string `form:"last_name,required"`
 		}
 
-		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (jsonRes, error) {
-			return jsonRes{Message: "Yo"}, nil
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req formReq) {}, func(opts *httph.FormHandlerOptions) {
+			opts.JSONErrors = true
 		})
 
-		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		res := httptest.NewRecorder()
+		h.ServeHTTP(res, createFormRequest(url.Values{}))
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"invalid request","Fields":{"first_name":"is required","last_name":"is required"}}`, readBody(t, res))
+	})
+
+	t.Run("decodes query parameters for GET requests", func(t *testing.T) {
+		type formReq struct {
+			Q    string
+			Page int
+		}
 
+		var got formReq
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req formReq) {
+			got = req
+		}, func(opts *httph.FormHandlerOptions) {
+			opts.IncludeQuery = true
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/search?q=hats&page=2", nil)
+		res := httptest.NewRecorder()
 		h.ServeHTTP(res, req)
 
 		is.Equal(t, http.StatusOK, res.Result().StatusCode)
-		is.Equal(t, `{"message":"Yo"}`, readBody(t, res))
+		is.Equal(t, formReq{Q: "hats", Page: 2}, got)
 	})
 
-	t.Run("parses request body from JSON", func(t *testing.T) {
-		type jsonReq struct {
-			Name string
+	t.Run("prefers body values over query parameters with IncludeQuery", func(t *testing.T) {
+		type formReq struct {
+			Q    string
+			Page int
 		}
 
-		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, req jsonReq) (any, error) {
-			is.Equal(t, "Me", req.Name)
-			return nil, nil
+		var got formReq
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req formReq) {
+			got = req
+		}, func(opts *httph.FormHandlerOptions) {
+			opts.IncludeQuery = true
 		})
 
-		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":"Me"}`))
-		req.Header.Set("Content-Type", "application/json")
+		vs := url.Values{}
+		vs.Set("q", "goats")
+		req := createFormRequest(vs)
+		req.URL.RawQuery = "q=hats&page=2"
 		res := httptest.NewRecorder()
-
 		h.ServeHTTP(res, req)
 
 		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, formReq{Q: "goats", Page: 2}, got)
 	})
 
-	t.Run("returns bad request if request body is not valid JSON", func(t *testing.T) {
-		type jsonReq struct {
-			Name string
+	t.Run("returns bad request on bad input values", func(t *testing.T) {
+		type formReq struct {
+			Age int
 		}
 
-		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, req jsonReq) (any, error) {
-			return nil, nil
-		})
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req formReq) {})
 
-		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{`))
-		req.Header.Set("Content-Type", "application/json")
+		vs := url.Values{}
+		vs.Set("age", "not a number")
+		req := createFormRequest(vs)
 		res := httptest.NewRecorder()
 
 		h.ServeHTTP(res, req)
 
 		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
-		is.Equal(t, `{"Error":"error decoding request body as JSON: unexpected EOF"}`, readBody(t, res))
+		is.True(t, strings.Contains(readBody(t, res), "cannot parse 'Age' as int"))
 	})
 
-	t.Run("returns error message if handler errors", func(t *testing.T) {
-		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
-			return nil, errors.New("oh no")
+	t.Run("returns bad request when there are too many form values", func(t *testing.T) {
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req struct{}) {}, func(opts *httph.FormHandlerOptions) {
+			opts.MaxFields = 2
 		})
 
-		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		vs := url.Values{}
+		vs.Set("name", "Me")
+		vs.Add("hobbies", "Hats")
+		vs.Add("hobbies", "Goats")
+		req := createFormRequest(vs)
 		res := httptest.NewRecorder()
 
 		h.ServeHTTP(res, req)
 
-		is.Equal(t, http.StatusInternalServerError, res.Result().StatusCode)
-		is.Equal(t, `{"Error":"oh no"}`, readBody(t, res))
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, "too many form fields, max is 2", readBody(t, res))
 	})
 
-	t.Run("returns error message with custom http status code if error satisfies statusCodeGiver", func(t *testing.T) {
-		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
-			return nil, &httpError{http.StatusTeapot}
-		})
+	t.Run("allows form values up to the default limit", func(t *testing.T) {
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req struct{}) {})
 
-		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		vs := url.Values{}
+		for i := 0; i < 1000; i++ {
+			vs.Add("v", "1")
+		}
+		req := createFormRequest(vs)
 		res := httptest.NewRecorder()
-
 		h.ServeHTTP(res, req)
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
 
-		is.Equal(t, http.StatusTeapot, res.Result().StatusCode)
-		is.Equal(t, `{"Error":"I'm a teapot"}`, readBody(t, res))
+		vs.Add("v", "1")
+		req = createFormRequest(vs)
+		res = httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
 	})
 
-	t.Run("returns error message if response body cannot be encoded to JSON", func(t *testing.T) {
-		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
-			return make(chan int), nil
-		})
+	t.Run("returns request entity too large if request body is too large", func(t *testing.T) {
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req tinyFormReq) {})
 
-		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		vs := url.Values{}
+		vs.Set("name", "Me")
+		req := createFormRequest(vs)
 		res := httptest.NewRecorder()
 
 		h.ServeHTTP(res, req)
 
-		is.Equal(t, http.StatusInternalServerError, res.Result().StatusCode)
-		is.Equal(t, `{"Error":"error encoding response body as JSON: json: unsupported type: chan int"}`, readBody(t, res))
+		is.Equal(t, http.StatusRequestEntityTooLarge, res.Result().StatusCode)
+		is.Equal(t, "request body too large, max is 1 bytes", readBody(t, res))
 	})
 
-	t.Run("returns custom status code if response struct satisfies statusCodeGiver", func(t *testing.T) {
-		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (jsonRes, error) {
-			return jsonRes{Message: "Yo"}, nil
+	t.Run("returns the validation status code from options on validation errors", func(t *testing.T) {
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req validatedFormReq) {}, func(opts *httph.FormHandlerOptions) {
+			opts.ValidationStatusCode = http.StatusUnprocessableEntity
 		})
 
-		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		res := httptest.NewRecorder()
+		h.ServeHTTP(res, createFormRequest(url.Values{}))
 
-		h.ServeHTTP(res, req)
-
-		is.Equal(t, http.StatusAccepted, res.Result().StatusCode)
-		is.Equal(t, `{"Message":"Yo"}`, readBody(t, res))
+		is.Equal(t, http.StatusUnprocessableEntity, res.Result().StatusCode)
+		is.Equal(t, "invalid form: invalid", readBody(t, res))
 	})
 
-	t.Run("returns bad request if request body is too large", func(t *testing.T) {
-		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ tinyJSONReq) (any, error) {
-			return nil, nil
+	t.Run("returns the validation status code from options on missing required fields", func(t *testing.T) {
+		type formReq struct {
+			Name string `form:"name,required"`
+		}
+
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req formReq) {}, func(opts *httph.FormHandlerOptions) {
+			opts.ValidationStatusCode = http.StatusUnprocessableEntity
 		})
 
-		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":"Me"}`))
-		req.Header.Set("Content-Type", "application/json")
 		res := httptest.NewRecorder()
+		h.ServeHTTP(res, createFormRequest(url.Values{}))
 
-		h.ServeHTTP(res, req)
+		is.Equal(t, http.StatusUnprocessableEntity, res.Result().StatusCode)
+	})
+
+	t.Run("returns bad request on parse errors even with a validation status code", func(t *testing.T) {
+		type formReq struct {
+			Age int
+		}
+
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req formReq) {}, func(opts *httph.FormHandlerOptions) {
+			opts.ValidationStatusCode = http.StatusUnprocessableEntity
+		})
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, createFormRequest(url.Values{"age": {"old"}}))
 
 		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
-		is.Equal(t, `{"Error":"error decoding request body as JSON: http: request body too large"}`, readBody(t, res))
 	})
-}
 
-func ExampleJSONHandler() {
-	type Req struct {
-		Name string
-	}
+	t.Run("validates with the request context if the request satisfies contextValidator", func(t *testing.T) {
+		var called bool
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req contextValidatedReq) {
+			called = true
+		})
+
+		req := createFormRequest(url.Values{"name": {"Me"}})
+		req = req.WithContext(context.WithValue(req.Context(), contextKey{}, "Me"))
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, "invalid form: name is taken", readBody(t, res))
+		is.True(t, !called)
+
+		req = createFormRequest(url.Values{"name": {"You"}})
+		req = req.WithContext(context.WithValue(req.Context(), contextKey{}, "Me"))
+		res = httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.True(t, called)
+	})
+
+	t.Run("returns bad request when Validate() returns error", func(t *testing.T) {
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req validatedFormReq) {})
+
+		vs := url.Values{}
+		vs.Set("name", "")
+		req := createFormRequest(vs)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, "invalid form: invalid", readBody(t, res))
+	})
+
+	t.Run("parses a multipart form with files into a struct", func(t *testing.T) {
+		type uploadReq struct {
+			Name   string
+			Avatar *multipart.FileHeader `form:"avatar,maxsize=1KB"`
+			Other  *multipart.FileHeader
+		}
+
+		var name, avatar string
+		var other *multipart.FileHeader
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req uploadReq) {
+			name = req.Name
+			other = req.Other
+			f, err := req.Avatar.Open()
+			is.NotError(t, err)
+			b, err := io.ReadAll(f)
+			is.NotError(t, err)
+			avatar = req.Avatar.Filename + ": " + string(b)
+		})
+
+		vs := url.Values{}
+		vs.Set("name", "Me")
+		req := createMultipartRequest(t, vs, multipartFile{field: "avatar", name: "me.png", content: "not really a png"})
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "Me", name)
+		is.Equal(t, "me.png: not really a png", avatar)
+		is.True(t, other == nil)
+	})
+
+	t.Run("parses multiple files for a field into a slice", func(t *testing.T) {
+		type uploadReq struct {
+			Title       string
+			Attachments []*multipart.FileHeader
+		}
+
+		var got uploadReq
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req uploadReq) {
+			got = req
+		}, func(opts *httph.FormHandlerOptions) {
+			opts.MaxMemoryBytes = 1
+		})
+
+		vs := url.Values{}
+		vs.Set("title", "Report")
+		req := createMultipartRequest(t, vs,
+			multipartFile{field: "attachments", name: "a.txt", content: "a"},
+			multipartFile{field: "attachments", name: "b.txt", content: "b"},
+		)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "Report", got.Title)
+		is.Equal(t, 2, len(got.Attachments))
+		is.Equal(t, "a.txt", got.Attachments[0].Filename)
+		is.Equal(t, "b.txt", got.Attachments[1].Filename)
+		_ = req.MultipartForm.RemoveAll()
+	})
+
+	t.Run("returns request entity too large naming the field when a file exceeds its max size", func(t *testing.T) {
+		type uploadReq struct {
+			Avatar   *multipart.FileHeader `form:"avatar,maxsize=1KB"`
+			Document *multipart.FileHeader `form:"document,maxsize=1.5KiB"`
+		}
+
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req uploadReq) {})
+
+		req := createMultipartRequest(t, nil,
+			multipartFile{field: "document", name: "doc.txt", content: strings.Repeat("a", 1536)},
+			multipartFile{field: "avatar", name: "me.png", content: strings.Repeat("a", 1001)},
+		)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusRequestEntityTooLarge, res.Result().StatusCode)
+		is.Equal(t, "file avatar is too large, max is 1KB", readBody(t, res))
+	})
+
+	t.Run("limits the size of untagged file fields matched case-insensitively", func(t *testing.T) {
+		type uploadReq struct {
+			Avatar *multipart.FileHeader `form:",maxsize=1KB"`
+		}
+
+		var called bool
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req uploadReq) {
+			called = true
+		})
+
+		req := createMultipartRequest(t, nil, multipartFile{field: "avatar", name: "me.png", content: strings.Repeat("a", 1001)})
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusRequestEntityTooLarge, res.Result().StatusCode)
+		is.Equal(t, "file Avatar is too large, max is 1KB", readBody(t, res))
+		is.True(t, !called)
+	})
+
+	t.Run("panics on invalid max size", func(t *testing.T) {
+		type uploadReq struct {
+			Avatar *multipart.FileHeader `form:"avatar,maxsize=1 lightyear"`
+		}
+
+		defer func() {
+			is.True(t, recover() != nil)
+		}()
+		httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req uploadReq) {})
+	})
+
+	t.Run("returns a line per field sorted by name when Validate() returns an error with field errors", func(t *testing.T) {
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req fieldErrorsFormReq) {})
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, createFormRequest(url.Values{}))
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, "invalid form:\nage: must be a number\nname: is required", readBody(t, res))
+	})
+
+	t.Run("returns JSON errors when configured", func(t *testing.T) {
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req fieldErrorsFormReq) {}, func(opts *httph.FormHandlerOptions) {
+			opts.JSONErrors = true
+		})
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, createFormRequest(url.Values{}))
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, "application/json", res.Header().Get("Content-Type"))
+		is.Equal(t, `{"Error":"invalid request","Fields":{"age":"must be a number","name":"is required"}}`, readBody(t, res))
+
+		h = httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req validatedFormReq) {}, func(opts *httph.FormHandlerOptions) {
+			opts.JSONErrors = true
+		})
+
+		res = httptest.NewRecorder()
+		h.ServeHTTP(res, createFormRequest(url.Values{}))
+
+		is.Equal(t, `{"Error":"invalid request: invalid"}`, readBody(t, res))
+	})
+
+	t.Run("returns JSON errors when negotiated with the Accept header", func(t *testing.T) {
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req struct{}) {}, func(opts *httph.FormHandlerOptions) {
+			opts.NegotiateErrors = true
+			opts.MaxFields = 0
+		})
+
+		vs := url.Values{}
+		vs.Set("name", "Me")
+
+		req := createFormRequest(vs)
+		req.Header.Set("Accept", "application/json")
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"too many form fields, max is 0"}`, readBody(t, res))
+
+		req = createFormRequest(vs)
+		req.Header.Set("Accept", "*/*")
+		res = httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, "too many form fields, max is 0", readBody(t, res))
+	})
+
+	t.Run("returns a line per field when Validate() returns a ValidationError", func(t *testing.T) {
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req fieldValidatedReq) {})
+
+		vs := url.Values{}
+		vs.Set("email", "me")
+		req := createFormRequest(vs)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, "invalid form:\nname: is required\nemail: must contain @", readBody(t, res))
+	})
+
+	t.Run("parses dotted and bracketed field names into nested structs", func(t *testing.T) {
+		type Country struct {
+			Code string
+		}
+		type Address struct {
+			Street  string
+			City    string
+			Country Country
+		}
+		type formReq struct {
+			Name     string
+			Address  Address
+			Shipping Address `form:"shipping_address"`
+			Note     string  `form:"note.text"`
+		}
+
+		var got formReq
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req formReq) {
+			got = req
+		})
+
+		vs := url.Values{}
+		vs.Set("name", "Me")
+		vs.Set("address.street", "Main Street")
+		vs.Set("address.city", "Copenhagen")
+		vs.Set("address.country.code", "DK")
+		vs.Set("shipping_address[city]", "Aarhus")
+		vs.Set("shipping_address[country][code]", "SE")
+		vs.Set("note.text", "Thanks")
+		req := createFormRequest(vs)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "Me", got.Name)
+		is.Equal(t, "Main Street", got.Address.Street)
+		is.Equal(t, "Copenhagen", got.Address.City)
+		is.Equal(t, "DK", got.Address.Country.Code)
+		is.Equal(t, "Aarhus", got.Shipping.City)
+		is.Equal(t, "SE", got.Shipping.Country.Code)
+		is.Equal(t, "Thanks", got.Note)
+	})
+
+	t.Run("parses dotted field names into embedded structs", func(t *testing.T) {
+		type Address struct {
+			Street, City string
+		}
+		type formReq struct {
+			Name string
+			Address
+		}
+
+		var got formReq
+		h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req formReq) {
+			got = req
+		})
+
+		vs := url.Values{}
+		vs.Set("name", "Me")
+		vs.Set("address.city", "Copenhagen")
+		req := createFormRequest(vs)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "Me", got.Name)
+		is.Equal(t, "Copenhagen", got.City)
+	})
+}
+
+func ExampleFormHandler() {
+	type Req struct {
+		Name string
+		Age  int
+	}
+
+	h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req Req) {
+		_, _ = fmt.Fprintf(w, "Hello %v, you are %v years old", req.Name, req.Age)
+	})
+
+	w := httptest.NewRecorder()
+	vs := url.Values{
+		"name": {"World"},
+		"age":  {"20"},
+	}
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(vs.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	h.ServeHTTP(w, r)
+
+	body, _ := io.ReadAll(w.Result().Body)
+	fmt.Println(string(body))
+	//Output: Hello World, you are 20 years old
+}
+
+func BenchmarkFormHandler(b *testing.B) {
+	type formReq struct {
+		Name    string `form:"name,required"`
+		Age     int
+		Hobbies []string
+	}
+
+	h := httph.FormHandler(func(w http.ResponseWriter, r *http.Request, req formReq) {})
+
+	vs := url.Values{}
+	vs.Set("name", "Me")
+	vs.Set("age", "20")
+	vs.Add("hobbies", "Hats")
+	vs.Add("hobbies", "Goats")
+	body := vs.Encode()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func BenchmarkJSONHandler(b *testing.B) {
+	type jsonReq struct {
+		Name string
+	}
+
+	h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, req jsonReq) (jsonRes, error) {
+		return jsonRes{Message: "Yo " + req.Name}, nil
+	})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":"Me"}`))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func TestFormHandlerE(t *testing.T) {
+	type formReq struct {
+		Name string `form:"name,required"`
+	}
+
+	type formRes struct {
+		Message string
+	}
+
+	t.Run("encodes the response struct as JSON", func(t *testing.T) {
+		h := httph.FormHandlerE(func(w http.ResponseWriter, r *http.Request, req formReq) (formRes, error) {
+			return formRes{Message: "Hello " + req.Name}, nil
+		})
+
+		vs := url.Values{}
+		vs.Set("name", "Me")
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, createFormRequest(vs))
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, `{"Message":"Hello Me"}`, readBody(t, res))
+	})
+
+	t.Run("returns error message with custom http status code if error satisfies statusCodeGiver", func(t *testing.T) {
+		h := httph.FormHandlerE(func(w http.ResponseWriter, r *http.Request, req formReq) (*formRes, error) {
+			return nil, &httpError{http.StatusConflict}
+		})
+
+		vs := url.Values{}
+		vs.Set("name", "Me")
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, createFormRequest(vs))
+
+		is.Equal(t, http.StatusConflict, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"Conflict"}`, readBody(t, res))
+	})
+
+	t.Run("returns bad request on validation failure without calling the function", func(t *testing.T) {
+		var called bool
+		h := httph.FormHandlerE(func(w http.ResponseWriter, r *http.Request, req formReq) (formRes, error) {
+			called = true
+			return formRes{}, nil
+		})
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, createFormRequest(url.Values{}))
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, "invalid form:\nname: is required", readBody(t, res))
+		is.True(t, !called)
+	})
+}
+
+func createFormRequest(vs url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(vs.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+// multipartFile to upload with createMultipartRequest.
+type multipartFile struct {
+	field, name, content string
+}
+
+func createMultipartRequest(t *testing.T, vs url.Values, files ...multipartFile) *http.Request {
+	t.Helper()
+
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	for k := range vs {
+		for _, v := range vs[k] {
+			if err := mw.WriteField(k, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for _, f := range files {
+		w, err := mw.CreateFormFile(f.field, f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(f.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", &b)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func readBody(t *testing.T, r *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	d, err := io.ReadAll(r.Result().Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(d))
+}
+
+type httpError struct {
+	code int
+}
+
+func (h *httpError) Error() string {
+	return http.StatusText(h.code)
+}
+
+func (h *httpError) StatusCode() int {
+	return h.code
+}
+
+type jsonRes struct {
+	Message string
+}
+
+func (j jsonRes) StatusCode() int {
+	return http.StatusAccepted
+}
+
+type vendorJSONRes struct {
+	Name string
+}
+
+func (v vendorJSONRes) ContentType() string {
+	return "application/vnd.example.user+json"
+}
+
+type createdJSONRes struct {
+	ID string
+}
+
+func (c createdJSONRes) StatusCode() int {
+	return http.StatusCreated
+}
+
+func (c createdJSONRes) Headers() http.Header {
+	return http.Header{"Location": {"/users/" + c.ID}}
+}
+
+type streamedJSONRes struct {
+	N   int
+	Err error
+}
+
+func (s streamedJSONRes) StatusCode() int {
+	return http.StatusCreated
+}
+
+func (s streamedJSONRes) WriteJSON(w io.Writer) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for i := 0; i < s.N; i++ {
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, `{"ID":%v,"Name":"item %v"}`, i, i); err != nil {
+			return err
+		}
+	}
+	if s.Err != nil {
+		return s.Err
+	}
+	_, err := io.WriteString(w, "]")
+	return err
+}
+
+// countingResponseWriter counts calls to Write and the size of the largest one.
+type countingResponseWriter struct {
+	*httptest.ResponseRecorder
+	writes, maxWrite int
+}
+
+func (c *countingResponseWriter) Write(p []byte) (int, error) {
+	c.writes++
+	c.maxWrite = max(c.maxWrite, len(p))
+	return c.ResponseRecorder.Write(p)
+}
+
+type validatedJSONReq struct {
+	Name string
+	Age  int
+}
+
+func (r validatedJSONReq) Validate() error {
+	var errs []error
+	if r.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if r.Age < 0 {
+		errs = append(errs, errors.New("age must be non-negative"))
+	}
+	return errors.Join(errs...)
+}
+
+type fieldValidatedReq struct {
+	Name  string
+	Email string
+}
+
+func (r fieldValidatedReq) Validate() error {
+	var ve httph.ValidationError
+	if r.Name == "" {
+		ve.Add("name", "is required")
+	}
+	if !strings.Contains(r.Email, "@") {
+		ve.Add("email", "must contain @")
+	}
+	return ve.Err()
+}
+
+type slowJSONRes struct{}
+
+func (s slowJSONRes) MarshalJSON() ([]byte, error) {
+	time.Sleep(100 * time.Millisecond)
+	return []byte("{}"), nil
+}
+
+type panickyJSONRes struct{}
+
+func (p panickyJSONRes) MarshalJSON() ([]byte, error) {
+	panic("oh no")
+}
+
+type tinyJSONReq struct {
+	Name string
+}
+
+func (t tinyJSONReq) MaxSizeBytes() int64 {
+	return 1
+}
+
+type largeJSONReq struct {
+	Name string
+}
+
+func (l largeJSONReq) MaxSizeBytes() int64 {
+	return 4 << 20
+}
+
+func TestJSONHandler(t *testing.T) {
+	t.Run("returns the same responses for many requests, on both success and error paths", func(t *testing.T) {
+		type jsonReq struct {
+			Name string
+		}
+
+		for _, timeout := range []time.Duration{0, time.Second} {
+			h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, req jsonReq) (jsonRes, error) {
+				if req.Name == "" {
+					return jsonRes{}, errors.New("no name")
+				}
+				return jsonRes{Message: "Yo " + req.Name}, nil
+			}, func(opts *httph.JSONHandlerOptions) {
+				opts.EncodeTimeout = timeout
+			})
+
+			for i := 0; i < 10; i++ {
+				name := strings.Repeat("a", i*1000)
+				req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":"`+name+`"}`))
+				res := httptest.NewRecorder()
+				h.ServeHTTP(res, req)
+
+				if name == "" {
+					is.Equal(t, http.StatusInternalServerError, res.Result().StatusCode)
+					is.Equal(t, `{"Error":"no name"}`, readBody(t, res))
+					continue
+				}
+				is.Equal(t, http.StatusAccepted, res.Result().StatusCode)
+				is.Equal(t, `{"Message":"Yo `+name+`"}`, readBody(t, res))
+
+				req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":`))
+				res = httptest.NewRecorder()
+				h.ServeHTTP(res, req)
+				is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+			}
+		}
+	})
+
+	t.Run("encodes response body to JSON", func(t *testing.T) {
+		type jsonRes struct {
+			Message string `json:"message"`
+		}
+
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (jsonRes, error) {
+			return jsonRes{Message: "Yo"}, nil
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "application/json", res.Result().Header.Get("Content-Type"))
+		is.Equal(t, `{"message":"Yo"}`, readBody(t, res))
+	})
+
+	t.Run("parses request body from JSON", func(t *testing.T) {
+		type jsonReq struct {
+			Name string
+		}
+
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, req jsonReq) (any, error) {
+			is.Equal(t, "Me", req.Name)
+			return nil, nil
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":"Me"}`))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+	})
+
+	t.Run("returns bad request if request body is not valid JSON", func(t *testing.T) {
+		type jsonReq struct {
+			Name string
+		}
+
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, req jsonReq) (any, error) {
+			return nil, nil
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{`))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, "application/json", res.Result().Header.Get("Content-Type"))
+		is.Equal(t, `{"Error":"error decoding request body as JSON: unexpected EOF"}`, readBody(t, res))
+	})
+
+	t.Run("returns bad request for unknown fields only if disallowed", func(t *testing.T) {
+		type jsonReq struct {
+			Name string
+		}
+
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, req jsonReq) (any, error) {
+			return nil, nil
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":"Me","Nmae":"Me"}`))
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+
+		h = httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, req jsonReq) (any, error) {
+			return nil, nil
+		}, func(opts *httph.JSONHandlerOptions) {
+			opts.DisallowUnknownFields = true
+		})
+
+		req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":"Me","Nmae":"Me"}`))
+		res = httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"error decoding request body as JSON: json: unknown field \"Nmae\""}`, readBody(t, res))
+	})
+
+	t.Run("returns error message if handler errors", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
+			return nil, errors.New("oh no")
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusInternalServerError, res.Result().StatusCode)
+		is.Equal(t, "application/json", res.Result().Header.Get("Content-Type"))
+		is.Equal(t, `{"Error":"oh no"}`, readBody(t, res))
+	})
+
+	t.Run("returns error message with custom http status code if error satisfies statusCodeGiver", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
+			return nil, &httpError{http.StatusTeapot}
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusTeapot, res.Result().StatusCode)
+		is.Equal(t, "application/json", res.Result().Header.Get("Content-Type"))
+		is.Equal(t, `{"Error":"I'm a teapot"}`, readBody(t, res))
+	})
+
+	t.Run("returns a custom error response shape with the usual status code", func(t *testing.T) {
+		type apiError struct {
+			Message string `json:"message"`
+			Code    string `json:"code"`
+		}
+
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
+			return nil, httph.HTTPError{Code: http.StatusTooManyRequests, Message: "your quota is exhausted"}
+		}, func(opts *httph.JSONHandlerOptions) {
+			opts.ErrorResponse = func(err error) any {
+				code := "internal"
+				var httpErr httph.HTTPError
+				if errors.As(err, &httpErr) && httpErr.Code == http.StatusTooManyRequests {
+					code = "quota_exhausted"
+				}
+				return map[string]apiError{"error": {Message: httpErr.Message, Code: code}}
+			}
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusTooManyRequests, res.Result().StatusCode)
+		is.Equal(t, "application/json", res.Result().Header.Get("Content-Type"))
+		is.Equal(t, `{"error":{"message":"your quota is exhausted","code":"quota_exhausted"}}`, readBody(t, res))
+	})
+
+	t.Run("returns a custom error response shape for decoding errors", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ struct{ Name string }) (any, error) {
+			return nil, nil
+		}, func(opts *httph.JSONHandlerOptions) {
+			opts.ErrorResponse = func(err error) any {
+				return map[string]string{"error": err.Error()}
+			}
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{`))
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, `{"error":"error decoding request body as JSON: unexpected EOF"}`, readBody(t, res))
+	})
+
+	t.Run("returns the message from an HTTPError", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
+			return nil, httph.HTTPError{Code: http.StatusTooManyRequests, Message: "your quota is exhausted"}
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusTooManyRequests, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"your quota is exhausted"}`, readBody(t, res))
+	})
+
+	t.Run("returns the status text from an HTTPError without a message", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
+			return nil, httph.HTTPError{Code: http.StatusTooManyRequests}
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusTooManyRequests, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"Too Many Requests"}`, readBody(t, res))
+	})
+
+	t.Run("returns error message if response body cannot be encoded to JSON", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
+			return make(chan int), nil
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusInternalServerError, res.Result().StatusCode)
+		is.Equal(t, "application/json", res.Result().Header.Get("Content-Type"))
+		is.Equal(t, `{"Error":"error encoding response body as JSON: json: unsupported type: chan int"}`, readBody(t, res))
+	})
+
+	t.Run("returns custom status code if response struct satisfies statusCodeGiver", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (jsonRes, error) {
+			return jsonRes{Message: "Yo"}, nil
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusAccepted, res.Result().StatusCode)
+		is.Equal(t, `{"Message":"Yo"}`, readBody(t, res))
+	})
+
+	t.Run("returns no content with an empty body for NoContent", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (httph.NoContent, error) {
+			return httph.NoContent{}, nil
+		})
+
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusNoContent, res.Result().StatusCode)
+		is.Equal(t, "", res.Result().Header.Get("Content-Type"))
+		is.Equal(t, "", readBody(t, res))
+	})
+
+	t.Run("returns no content with an empty body for a nil pointer response", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (*jsonRes, error) {
+			return nil, nil
+		})
+
+		req := httptest.NewRequest(http.MethodPut, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusNoContent, res.Result().StatusCode)
+		is.Equal(t, "", readBody(t, res))
+	})
+
+	t.Run("returns custom content type if response struct satisfies contentTypeGiver", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (vendorJSONRes, error) {
+			w.Header().Set("Content-Type", "text/plain")
+			return vendorJSONRes{Name: "Me"}, nil
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "application/vnd.example.user+json", res.Header().Get("Content-Type"))
+		is.Equal(t, `{"Name":"Me"}`, readBody(t, res))
+	})
+
+	t.Run("records present fields to tell omitted fields from null ones", func(t *testing.T) {
+		type patchReq struct {
+			Name *string
+			Age  *int
+			Bio  *string
+		}
+
+		var req patchReq
+		var present map[string]bool
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, pr patchReq) (any, error) {
+			req = pr
+			present = httph.PresentFieldsFromContext(r.Context())
+			return nil, nil
+		}, func(opts *httph.JSONHandlerOptions) {
+			opts.PresentFields = true
+		})
+
+		r := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"Name":"Me","Bio":null}`))
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, r)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "Me", *req.Name)
+		is.True(t, req.Age == nil)
+		is.True(t, req.Bio == nil)
+		is.Equal(t, 2, len(present))
+		is.True(t, present["Name"])
+		is.True(t, present["Bio"])
+		is.True(t, !present["Age"])
+	})
+
+	t.Run("does not record present fields by default", func(t *testing.T) {
+		var present map[string]bool
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ struct{ Name *string }) (any, error) {
+			present = httph.PresentFieldsFromContext(r.Context())
+			return nil, nil
+		})
+
+		r := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"Name":"Me"}`))
+		h.ServeHTTP(httptest.NewRecorder(), r)
+
+		is.True(t, present == nil)
+	})
+
+	t.Run("returns bad request for non-object bodies when recording present fields", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ struct{ Name *string }) (any, error) {
+			return nil, nil
+		}, func(opts *httph.JSONHandlerOptions) {
+			opts.PresentFields = true
+		})
+
+		r := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`[1]`))
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, r)
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+	})
+
+	t.Run("decodes numbers as json.Number if UseNumber is set", func(t *testing.T) {
+		var id any
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, req struct{ ID any }) (any, error) {
+			id = req.ID
+			return nil, nil
+		}, func(opts *httph.JSONHandlerOptions) {
+			opts.UseNumber = true
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"ID":12345678901234567890}`))
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		n, ok := id.(json.Number)
+		is.True(t, ok)
+		is.Equal(t, "12345678901234567890", n.String())
+	})
+
+	t.Run("decodes numbers as float64 by default", func(t *testing.T) {
+		var id any
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, req struct{ ID any }) (any, error) {
+			id = req.ID
+			return nil, nil
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"ID":12345678901234567890}`))
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		_, ok := id.(float64)
+		is.True(t, ok)
+	})
+
+	t.Run("indents the response body if Indent is set", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
+			return map[string]any{"message": "Yo", "tags": []string{"a"}}, nil
+		}, func(opts *httph.JSONHandlerOptions) {
+			opts.Indent = "  "
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "{\n  \"message\": \"Yo\",\n  \"tags\": [\n    \"a\"\n  ]\n}\n", res.Body.String())
+	})
+
+	t.Run("returns compact JSON by default", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
+			return map[string]any{"message": "Yo", "tags": []string{"a"}}, nil
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, `{"message":"Yo","tags":["a"]}`+"\n", res.Body.String())
+	})
+
+	t.Run("keeps headers set in the handler function", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (jsonRes, error) {
+			w.Header().Set("Location", "/messages/1")
+			w.Header().Set("Cache-Control", "no-cache")
+			return jsonRes{Message: "Yo"}, nil
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusAccepted, res.Result().StatusCode)
+		is.Equal(t, "/messages/1", res.Header().Get("Location"))
+		is.Equal(t, "no-cache", res.Header().Get("Cache-Control"))
+		is.Equal(t, `{"Message":"Yo"}`, readBody(t, res))
+	})
+
+	t.Run("sets headers if response struct satisfies headerGiver", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (createdJSONRes, error) {
+			return createdJSONRes{ID: "123"}, nil
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusCreated, res.Result().StatusCode)
+		is.Equal(t, "/users/123", res.Header().Get("Location"))
+		is.Equal(t, "application/json", res.Header().Get("Content-Type"))
+		is.Equal(t, `{"ID":"123"}`, readBody(t, res))
+	})
+
+	t.Run("does not set headers from headerGiver on a nil pointer response", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (*createdJSONRes, error) {
+			return nil, nil
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusNoContent, res.Result().StatusCode)
+		is.Equal(t, "", res.Header().Get("Location"))
+	})
+
+	t.Run("returns request entity too large if request body is too large", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ tinyJSONReq) (any, error) {
+			return nil, nil
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":"Me"}`))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusRequestEntityTooLarge, res.Result().StatusCode)
+		is.Equal(t, "application/json", res.Result().Header.Get("Content-Type"))
+		is.Equal(t, `{"Error":"request body too large, max is 1 bytes"}`, readBody(t, res))
+	})
+
+	t.Run("returns request entity too large if request body is larger than the default max size", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
+			return nil, nil
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":"`+strings.Repeat("a", 2<<20)+`"}`))
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusRequestEntityTooLarge, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"request body too large, max is 1048576 bytes"}`, readBody(t, res))
+	})
+
+	t.Run("limits the request body to the max size option", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
+			return nil, nil
+		}, func(opts *httph.JSONHandlerOptions) {
+			opts.MaxSizeBytes = 8
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":"Me"}`))
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusRequestEntityTooLarge, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"request body too large, max is 8 bytes"}`, readBody(t, res))
+	})
+
+	t.Run("does not limit the request body if max size option is negative", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
+			return nil, nil
+		}, func(opts *httph.JSONHandlerOptions) {
+			opts.MaxSizeBytes = -1
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":"`+strings.Repeat("a", 2<<20)+`"}`))
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+	})
+
+	t.Run("uses the max size from the request struct instead of the default", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ largeJSONReq) (any, error) {
+			return nil, nil
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":"`+strings.Repeat("a", 2<<20)+`"}`))
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+	})
+
+	t.Run("returns error message if encoding the response body times out", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (slowJSONRes, error) {
+			return slowJSONRes{}, nil
+		}, func(opts *httph.JSONHandlerOptions) {
+			opts.EncodeTimeout = time.Millisecond
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusInternalServerError, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"error encoding response body as JSON: timeout"}`, readBody(t, res))
+	})
+
+	t.Run("encodes response body within the encoding timeout", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (jsonRes, error) {
+			return jsonRes{Message: "Yo"}, nil
+		}, func(opts *httph.JSONHandlerOptions) {
+			opts.EncodeTimeout = time.Second
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusAccepted, res.Result().StatusCode)
+		is.Equal(t, `{"Message":"Yo"}`, readBody(t, res))
+	})
+
+	t.Run("returns error message if encoding panics with an encoding timeout", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (panickyJSONRes, error) {
+			return panickyJSONRes{}, nil
+		}, func(opts *httph.JSONHandlerOptions) {
+			opts.EncodeTimeout = time.Second
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusInternalServerError, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"error encoding response body as JSON: panic: oh no"}`, readBody(t, res))
+	})
+
+	t.Run("wraps response in JSONP callback when enabled", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (jsonRes, error) {
+			return jsonRes{Message: "Yo"}, nil
+		}, func(opts *httph.JSONHandlerOptions) {
+			opts.JSONPCallbackParam = "callback"
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/?callback=app.onData", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusAccepted, res.Result().StatusCode)
+		is.Equal(t, "application/javascript; charset=utf-8", res.Result().Header.Get("Content-Type"))
+		is.Equal(t, `/**/app.onData({"Message":"Yo"});`, readBody(t, res))
+	})
+
+	t.Run("returns bad request for invalid JSONP callback names", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (jsonRes, error) {
+			return jsonRes{Message: "Yo"}, nil
+		}, func(opts *httph.JSONHandlerOptions) {
+			opts.JSONPCallbackParam = "callback"
+		})
+
+		for _, callback := range []string{"", "alert(1)//", "a..b", "1a", "<script>"} {
+			req := httptest.NewRequest(http.MethodGet, "/?callback="+url.QueryEscape(callback), nil)
+			res := httptest.NewRecorder()
+
+			h.ServeHTTP(res, req)
+
+			is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+			is.Equal(t, `{"Error":"invalid JSONP callback"}`, readBody(t, res))
+		}
+	})
+
+	t.Run("does not use JSONP by default or for other methods than GET", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (jsonRes, error) {
+			return jsonRes{Message: "Yo"}, nil
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/?callback=cb", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		is.Equal(t, `{"Message":"Yo"}`, readBody(t, res))
+
+		h = httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (jsonRes, error) {
+			return jsonRes{Message: "Yo"}, nil
+		}, func(opts *httph.JSONHandlerOptions) {
+			opts.JSONPCallbackParam = "callback"
+		})
+
+		req = httptest.NewRequest(http.MethodPost, "/?callback=cb", nil)
+		res = httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		is.Equal(t, `{"Message":"Yo"}`, readBody(t, res))
+	})
+
+	t.Run("returns bad request when Validate() returns error", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ validatedJSONReq) (any, error) {
+			return nil, nil
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":""}`))
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, "application/json", res.Result().Header.Get("Content-Type"))
+		is.Equal(t, `{"Error":"invalid request","Errors":["name is required"]}`, readBody(t, res))
+	})
+
+	t.Run("returns the validation status code from options on validation errors", func(t *testing.T) {
+		newHandler := func(code int) http.HandlerFunc {
+			return httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ validatedJSONReq) (any, error) {
+				return nil, nil
+			}, func(opts *httph.JSONHandlerOptions) {
+				opts.ValidationStatusCode = code
+			})
+		}
+
+		res := httptest.NewRecorder()
+		newHandler(http.StatusUnprocessableEntity).ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":""}`)))
+		is.Equal(t, http.StatusUnprocessableEntity, res.Result().StatusCode)
+
+		res = httptest.NewRecorder()
+		newHandler(0).ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":""}`)))
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+
+		res = httptest.NewRecorder()
+		newHandler(http.StatusUnprocessableEntity).ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":`)))
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+	})
+
+	t.Run("validates with the request context if the request satisfies contextValidator", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, req contextValidatedReq) (any, error) {
+			return nil, nil
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":"Me"}`))
+		req = req.WithContext(context.WithValue(req.Context(), contextKey{}, "Me"))
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"invalid request: name is taken"}`, readBody(t, res))
+
+		req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":"You"}`))
+		req = req.WithContext(context.WithValue(req.Context(), contextKey{}, "Me"))
+		res = httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+	})
+
+	t.Run("returns each joined validation error separately", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ validatedJSONReq) (any, error) {
+			return nil, nil
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":"","Age":-1}`))
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"invalid request","Errors":["name is required","age must be non-negative"]}`, readBody(t, res))
+	})
+
+	t.Run("returns a single validation error as is", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ validatedFormReq) (any, error) {
+			return nil, nil
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"invalid request: invalid"}`, readBody(t, res))
+	})
+
+	t.Run("includes the request ID in error responses when configured", func(t *testing.T) {
+		type ctxKey struct{}
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
+			return nil, errors.New("oh no")
+		}, func(opts *httph.JSONHandlerOptions) {
+			opts.RequestIDField = "RequestID"
+			opts.RequestIDFunc = func(ctx context.Context) string {
+				id, _ := ctx.Value(ctxKey{}).(string)
+				return id
+			}
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(context.WithValue(req.Context(), ctxKey{}, "abc123"))
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusInternalServerError, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"oh no","RequestID":"abc123"}`, readBody(t, res))
+
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		res = httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, `{"Error":"oh no"}`, readBody(t, res))
+	})
+
+	t.Run("includes the request ID in success responses that are objects when configured", func(t *testing.T) {
+		type ctxKey struct{}
+		withIDFunc := func(opts *httph.JSONHandlerOptions) {
+			opts.RequestIDField = "RequestID"
+			opts.RequestIDFunc = func(ctx context.Context) string {
+				id, _ := ctx.Value(ctxKey{}).(string)
+				return id
+			}
+		}
+		request := func(h http.Handler, id string) string {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if id != "" {
+				req = req.WithContext(context.WithValue(req.Context(), ctxKey{}, id))
+			}
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+			is.Equal(t, http.StatusOK, res.Result().StatusCode)
+			return readBody(t, res)
+		}
+
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (map[string]string, error) {
+			return map[string]string{"Name": "Dingo"}, nil
+		}, withIDFunc)
+		is.Equal(t, `{"Name":"Dingo","RequestID":"abc123"}`, request(h, "abc123"))
+		is.Equal(t, `{"Name":"Dingo"}`, request(h, ""))
+
+		h = httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (map[string]string, error) {
+			return map[string]string{"Name": "Dingo"}, nil
+		}, withIDFunc, func(opts *httph.JSONHandlerOptions) {
+			opts.Indent = "  "
+		})
+		is.Equal(t, "{\n  \"Name\": \"Dingo\",\n  \"RequestID\": \"abc123\"\n}", request(h, "abc123"))
+
+		h = httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (struct{}, error) {
+			return struct{}{}, nil
+		}, withIDFunc)
+		is.Equal(t, `{"RequestID":"abc123"}`, request(h, "abc123"))
+
+		h = httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) ([]string, error) {
+			return []string{"Dingo"}, nil
+		}, withIDFunc)
+		is.Equal(t, `["Dingo"]`, request(h, "abc123"))
+	})
+
+	t.Run("returns field errors when Validate() returns a ValidationError", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ fieldValidatedReq) (any, error) {
+			return nil, nil
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Name":"Me"}`))
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"invalid request","Fields":{"email":"must contain @"}}`, readBody(t, res))
+	})
+
+	t.Run("returns field errors when the handler returns a ValidationError", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
+			var ve httph.ValidationError
+			ve.Add("name", "is taken")
+			return nil, fmt.Errorf("error creating user: %w", &ve)
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"invalid request","Fields":{"name":"is taken"}}`, readBody(t, res))
+	})
+
+	t.Run("streams the response if it satisfies jsonStreamer", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (streamedJSONRes, error) {
+			return streamedJSONRes{N: 100_000}, nil
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := &countingResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusCreated, res.Code)
+		is.Equal(t, "application/json", res.Header().Get("Content-Type"))
+		is.True(t, res.Body.Len() > 2_000_000)
+		is.True(t, res.writes >= 100_000)
+		is.True(t, res.maxWrite < 100)
+
+		var items []struct {
+			ID   int
+			Name string
+		}
+		is.NotError(t, json.Unmarshal(res.Body.Bytes(), &items))
+		is.Equal(t, 100_000, len(items))
+		is.Equal(t, "item 99999", items[99_999].Name)
+	})
+
+	t.Run("streams the response as JSONP", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (streamedJSONRes, error) {
+			return streamedJSONRes{N: 1}, nil
+		}, func(opts *httph.JSONHandlerOptions) {
+			opts.JSONPCallbackParam = "callback"
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/?callback=cb", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, "application/javascript; charset=utf-8", res.Header().Get("Content-Type"))
+		is.Equal(t, `/**/cb([{"ID":0,"Name":"item 0"}]);`, readBody(t, res))
+	})
+
+	t.Run("keeps the status code on streaming errors", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (streamedJSONRes, error) {
+			return streamedJSONRes{N: 1, Err: errors.New("oh no")}, nil
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusCreated, res.Code)
+		is.Equal(t, `[{"ID":0,"Name":"item 0"}`, readBody(t, res))
+	})
+}
+
+func ExampleJSONHandler() {
+	type Req struct {
+		Name string
+	}
 
 	type Res struct {
 		Message string
@@ -320,6 +1972,34 @@ func TestNoClickjacking(t *testing.T) {
 	})
 }
 
+func BenchmarkContentSecurityPolicy(b *testing.B) {
+	b.Run("static", func(b *testing.B) {
+		h := httph.ContentSecurityPolicy(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			h.ServeHTTP(res, req)
+		}
+	})
+
+	b.Run("nonce", func(b *testing.B) {
+		h := httph.ContentSecurityPolicy(func(opts *httph.ContentSecurityPolicyOptions) {
+			opts.Nonce = true
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			h.ServeHTTP(res, req)
+		}
+	})
+}
+
 func TestContentSecurityPolicy(t *testing.T) {
 	t.Run("restrict everything to 'none' except images, styles, scripts, and fonts", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -333,6 +2013,22 @@ func TestContentSecurityPolicy(t *testing.T) {
 			res.Result().Header.Get("Content-Security-Policy"))
 	})
 
+	t.Run("sets the same policy on every request, and calls the options function once", func(t *testing.T) {
+		var calls int
+		h := httph.ContentSecurityPolicy(func(opts *httph.ContentSecurityPolicyOptions) {
+			calls++
+			opts.ConnectSrc = "'self' https://api.example.com"
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		for i := 0; i < 3; i++ {
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+			is.Equal(t, "default-src 'none'; connect-src 'self' https://api.example.com; font-src 'self'; img-src 'self'; script-src 'self'; style-src 'self'",
+				res.Result().Header.Get("Content-Security-Policy"))
+		}
+		is.Equal(t, 1, calls)
+	})
+
 	t.Run("can set directives with options function", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		res := httptest.NewRecorder()
@@ -345,9 +2041,128 @@ func TestContentSecurityPolicy(t *testing.T) {
 		h := httph.ContentSecurityPolicy(optsFunc)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
 		h.ServeHTTP(res, req)
 
-		is.Equal(t, http.StatusOK, res.Result().StatusCode)
-		is.Equal(t, "default-src https:; img-src 'self'; style-src 'self'",
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "default-src https:; img-src 'self'; style-src 'self'",
+			res.Result().Header.Get("Content-Security-Policy"))
+	})
+
+	t.Run("expands references to named source lists", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		optsFunc := func(opts *httph.ContentSecurityPolicyOptions) {
+			opts.Sources = map[string]string{
+				"cdn":       "https://cdn.example.com https://static.example.com",
+				"analytics": "https://analytics.example.com",
+			}
+			opts.ScriptSrc = "'self' " + httph.CSPRef("cdn") + " " + httph.CSPRef("analytics")
+			opts.ConnectSrc = httph.CSPRef("analytics")
+		}
+		h := httph.ContentSecurityPolicy(optsFunc)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, "default-src 'none'; connect-src https://analytics.example.com; font-src 'self'; img-src 'self'; "+
+			"script-src 'self' https://cdn.example.com https://static.example.com https://analytics.example.com; style-src 'self'",
+			res.Result().Header.Get("Content-Security-Policy"))
+	})
+
+	t.Run("joins list directives with spaces, and omits empty lists", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h := httph.ContentSecurityPolicy(func(opts *httph.ContentSecurityPolicyOptions) {
+			opts.ImgSrcList = []string{"'self'", "https://cdn.example.com", "data:"}
+			opts.ScriptSrcList = []string{"'self'", httph.CSPRef("cdn")}
+			opts.FontSrcList = []string{}
+			opts.Sources = map[string]string{"cdn": "https://cdn.example.com"}
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, "default-src 'none'; img-src 'self' https://cdn.example.com data:; script-src 'self' https://cdn.example.com; style-src 'self'",
+			res.Result().Header.Get("Content-Security-Policy"))
+	})
+
+	t.Run("sets the report-only header instead if enabled", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h := httph.ContentSecurityPolicy(func(opts *httph.ContentSecurityPolicyOptions) {
+			opts.ReportOnly = true
+			opts.ReportTo = "csp-endpoint"
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, "default-src 'none'; font-src 'self'; img-src 'self'; script-src 'self'; style-src 'self'; report-to csp-endpoint",
+			res.Result().Header.Get("Content-Security-Policy-Report-Only"))
+		_, ok := res.Result().Header["Content-Security-Policy"]
+		is.True(t, !ok)
+	})
+
+	t.Run("adds directives without values", func(t *testing.T) {
+		for _, test := range []struct {
+			optsFunc func(opts *httph.ContentSecurityPolicyOptions)
+			expected string
+		}{
+			{func(opts *httph.ContentSecurityPolicyOptions) { opts.UpgradeInsecureRequests = true }, "upgrade-insecure-requests"},
+			{func(opts *httph.ContentSecurityPolicyOptions) { opts.BlockAllMixedContent = true }, "block-all-mixed-content"},
+			{func(opts *httph.ContentSecurityPolicyOptions) {
+				opts.UpgradeInsecureRequests = true
+				opts.BlockAllMixedContent = true
+				opts.ReportTo = "csp-endpoint"
+			}, "upgrade-insecure-requests; block-all-mixed-content; report-to csp-endpoint"},
+		} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			res := httptest.NewRecorder()
+
+			h := httph.ContentSecurityPolicy(test.optsFunc)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			h.ServeHTTP(res, req)
+
+			is.Equal(t, "default-src 'none'; font-src 'self'; img-src 'self'; script-src 'self'; style-src 'self'; "+test.expected,
+				res.Result().Header.Get("Content-Security-Policy"))
+		}
+	})
+
+	t.Run("adds a per-request nonce to script and style directives", func(t *testing.T) {
+		var nonce string
+		h := httph.ContentSecurityPolicy(func(opts *httph.ContentSecurityPolicyOptions) {
+			opts.Nonce = true
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce = httph.CSPNonceFromContext(r.Context())
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.True(t, len(nonce) > 0)
+		is.Equal(t, "default-src 'none'; font-src 'self'; img-src 'self'; script-src 'self' 'nonce-"+nonce+"'; style-src 'self' 'nonce-"+nonce+"'",
 			res.Result().Header.Get("Content-Security-Policy"))
+
+		first := nonce
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		is.True(t, first != nonce)
+	})
+
+	t.Run("has no nonce by default", func(t *testing.T) {
+		var nonce string
+		h := httph.ContentSecurityPolicy(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce = httph.CSPNonceFromContext(r.Context())
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		is.Equal(t, "", nonce)
+	})
+
+	t.Run("panics on reference to unknown named source list", func(t *testing.T) {
+		defer func() {
+			is.Equal(t, any("unknown CSP source list cdn"), recover())
+		}()
+
+		httph.ContentSecurityPolicy(func(opts *httph.ContentSecurityPolicyOptions) {
+			opts.ScriptSrc = httph.CSPRef("cdn")
+		})
 	})
 }
 
@@ -396,6 +2211,317 @@ func TestGoGet(t *testing.T) {
 		is.True(t, called)
 	})
 
+	t.Run("serves HTML for the domain host if checking the host", func(t *testing.T) {
+		h := httph.GoGet(httph.GoGetOptions{
+			Domain:    "maragu.dev",
+			Modules:   []string{"httph"},
+			URLPrefix: "https://github.com/maragudk",
+			CheckHost: true,
+		})
+
+		for _, host := range []string{"maragu.dev", "Maragu.dev:8080"} {
+			req := httptest.NewRequest(http.MethodGet, "/httph?go-get=1", nil)
+			req.Host = host
+			res := httptest.NewRecorder()
+			h(http.NotFoundHandler()).ServeHTTP(res, req)
+
+			is.Equal(t, http.StatusOK, res.Result().StatusCode)
+			is.Equal(t, goGetHTML, res.Body.String())
+		}
+	})
+
+	t.Run("passes through requests for other hosts if checking the host", func(t *testing.T) {
+		h := httph.GoGet(httph.GoGetOptions{
+			Domain:    "maragu.dev",
+			Modules:   []string{"httph"},
+			URLPrefix: "https://github.com/maragudk",
+			CheckHost: true,
+		})
+
+		for _, path := range []string{"/httph?go-get=1", "/httph"} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Host = "www.maragu.dev"
+			res := httptest.NewRecorder()
+
+			called := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			})
+			h(next).ServeHTTP(res, req)
+
+			is.Equal(t, http.StatusOK, res.Result().StatusCode)
+			is.True(t, called)
+		}
+	})
+
+	t.Run("serves HTML only for configured hosts if set", func(t *testing.T) {
+		h := httph.GoGet(httph.GoGetOptions{
+			Domain:    "maragu.dev",
+			Modules:   []string{"httph"},
+			URLPrefix: "https://github.com/maragudk",
+			CheckHost: true,
+			Hosts:     []string{"go.maragu.dev", "localhost"},
+		})
+
+		for host, code := range map[string]int{"go.maragu.dev": http.StatusOK, "localhost:8080": http.StatusOK, "maragu.dev": http.StatusNotFound} {
+			req := httptest.NewRequest(http.MethodGet, "/httph?go-get=1", nil)
+			req.Host = host
+			res := httptest.NewRecorder()
+			h(http.NotFoundHandler()).ServeHTTP(res, req)
+
+			is.Equal(t, code, res.Result().StatusCode)
+		}
+	})
+
+	t.Run("responds with not found for unknown modules if strict", func(t *testing.T) {
+		h := httph.GoGet(httph.GoGetOptions{
+			Domain:        "maragu.dev",
+			Modules:       []string{"httph"},
+			URLPrefix:     "https://github.com/maragudk",
+			StrictModules: true,
+		})
+
+		tests := map[string]int{
+			"/httph?go-get=1":       http.StatusOK,
+			"/foo?go-get=1":         http.StatusNotFound,
+			"/foo.v2/bar?go-get=1":  http.StatusNotFound,
+			"/foo":                  http.StatusNotFound,
+			"/foo/bar":              http.StatusNotFound,
+			"/":                     http.StatusTeapot,
+			"/?go-get=1":            http.StatusTeapot,
+			"/favicon.ico":          http.StatusTeapot,
+			"/.well-known/security": http.StatusTeapot,
+		}
+		for path, code := range tests {
+			t.Run(path, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				res := httptest.NewRecorder()
+
+				next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusTeapot)
+				})
+				h(next).ServeHTTP(res, req)
+
+				is.Equal(t, code, res.Result().StatusCode)
+			})
+		}
+	})
+
+	t.Run("passes through unknown modules if not strict", func(t *testing.T) {
+		h := httph.GoGet(httph.GoGetOptions{
+			Domain:    "maragu.dev",
+			Modules:   []string{"httph"},
+			URLPrefix: "https://github.com/maragudk",
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/foo?go-get=1", nil)
+		res := httptest.NewRecorder()
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})
+		h(next).ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusTeapot, res.Result().StatusCode)
+	})
+
+	t.Run("serves the module root HTML for packages in a module", func(t *testing.T) {
+		h := httph.GoGet(httph.GoGetOptions{
+			Domain:    "maragu.dev",
+			Modules:   []string{"httph"},
+			URLPrefix: "https://github.com/maragudk",
+		})
+
+		for _, path := range []string{"/httph/sub/pkg?go-get=1", "/httph/?go-get=1"} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			res := httptest.NewRecorder()
+			h(http.NotFoundHandler()).ServeHTTP(res, req)
+
+			is.Equal(t, http.StatusOK, res.Result().StatusCode)
+			is.Equal(t, goGetHTML, res.Body.String())
+		}
+	})
+
+	t.Run("serves HTML for modules matching a pattern, with exact matches first", func(t *testing.T) {
+		h := httph.GoGet(httph.GoGetOptions{
+			Domain:    "maragu.dev",
+			Modules:   []string{"tools/*", "tools/special", "x-*"},
+			URLPrefix: "https://github.com/maragudk",
+		})
+
+		for _, test := range []struct {
+			path     string
+			expected string
+		}{
+			{"/tools/gen?go-get=1", `<meta name="go-import" content="maragu.dev/tools/gen git https://github.com/maragudk/tools/gen">`},
+			{"/tools/special/sub?go-get=1", `<meta name="go-import" content="maragu.dev/tools/special git https://github.com/maragudk/tools/special">`},
+			{"/x-foo?go-get=1", `<meta name="go-import" content="maragu.dev/x-foo git https://github.com/maragudk/x-foo">`},
+		} {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			res := httptest.NewRecorder()
+			h(http.NotFoundHandler()).ServeHTTP(res, req)
+
+			is.Equal(t, http.StatusOK, res.Result().StatusCode)
+			is.True(t, strings.HasPrefix(res.Body.String(), test.expected))
+		}
+	})
+
+	t.Run("uses per-module URL prefixes, falling back to the global one", func(t *testing.T) {
+		h := httph.GoGet(httph.GoGetOptions{
+			Domain:  "maragu.dev",
+			Modules: []string{"httph"},
+			ModuleConfigs: []httph.GoGetModule{
+				{Name: "foo", URLPrefix: "https://gitlab.com/maragudk/"},
+				{Name: "bar", URLPrefix: "https://hg.example.com/maragudk", VCS: "hg"},
+			},
+			URLPrefix: "https://github.com/maragudk",
+		})
+
+		for _, test := range []struct {
+			path     string
+			expected string
+		}{
+			{"/foo?go-get=1", `<meta name="go-import" content="maragu.dev/foo git https://gitlab.com/maragudk/foo">`},
+			{"/bar?go-get=1", `<meta name="go-import" content="maragu.dev/bar hg https://hg.example.com/maragudk/bar">`},
+			{"/httph?go-get=1", `<meta name="go-import" content="maragu.dev/httph git https://github.com/maragudk/httph">`},
+		} {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			res := httptest.NewRecorder()
+			h(http.NotFoundHandler()).ServeHTTP(res, req)
+
+			is.Equal(t, http.StatusOK, res.Result().StatusCode)
+			is.True(t, strings.HasPrefix(res.Body.String(), test.expected))
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/foo", nil)
+		res := httptest.NewRecorder()
+		h(http.NotFoundHandler()).ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusPermanentRedirect, res.Result().StatusCode)
+		is.Equal(t, "https://gitlab.com/maragudk/foo", res.Result().Header.Get("Location"))
+	})
+
+	t.Run("uses the configured VCS", func(t *testing.T) {
+		h := httph.GoGet(httph.GoGetOptions{
+			Domain:    "maragu.dev",
+			Modules:   []string{"httph"},
+			URLPrefix: "https://fossil.example.com",
+			VCS:       "fossil",
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/httph?go-get=1", nil)
+		res := httptest.NewRecorder()
+		h(http.NotFoundHandler()).ServeHTTP(res, req)
+
+		is.True(t, strings.HasPrefix(res.Body.String(), `<meta name="go-import" content="maragu.dev/httph fossil https://fossil.example.com/httph">`))
+	})
+
+	t.Run("serves HTML from a custom template", func(t *testing.T) {
+		h := httph.GoGet(httph.GoGetOptions{
+			Domain:    "maragu.dev",
+			Modules:   []string{"httph"},
+			URLPrefix: "https://github.com/maragudk",
+			Template: template.Must(template.New("").Parse(
+				`<meta name="go-import" content="{{ .Domain }}/{{ .Module }} {{ .VCS }} {{ .URLPrefix }}/{{ .Module }}">` +
+					`<h1>{{ .Module }} is a nice module</h1>`)),
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/httph?go-get=1", nil)
+		res := httptest.NewRecorder()
+		h(http.NotFoundHandler()).ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, `<meta name="go-import" content="maragu.dev/httph git https://github.com/maragudk/httph">`+
+			`<h1>httph is a nice module</h1>`, res.Body.String())
+	})
+
+	t.Run("panics on a custom template that produces no output", func(t *testing.T) {
+		defer func() {
+			is.Equal(t, any("invalid template"), recover())
+		}()
+		httph.GoGet(httph.GoGetOptions{
+			Domain:    "maragu.dev",
+			Modules:   []string{"httph"},
+			URLPrefix: "https://github.com/maragudk",
+			Template:  template.Must(template.New("").Parse(`{{ if false }}nope{{ end }}`)),
+		})
+	})
+
+	t.Run("panics on a custom template that can't be executed", func(t *testing.T) {
+		defer func() {
+			is.Equal(t, any("invalid template"), recover())
+		}()
+		httph.GoGet(httph.GoGetOptions{
+			Domain:    "maragu.dev",
+			Modules:   []string{"httph"},
+			URLPrefix: "https://github.com/maragudk",
+			Template:  template.Must(template.New("").Parse(`{{ .Nope }}`)),
+		})
+	})
+
+	t.Run("panics on unknown VCS", func(t *testing.T) {
+		defer func() {
+			is.Equal(t, any("invalid VCS cvs"), recover())
+		}()
+		httph.GoGet(httph.GoGetOptions{
+			Domain:    "maragu.dev",
+			Modules:   []string{"httph"},
+			URLPrefix: "https://github.com/maragudk",
+			VCS:       "cvs",
+		})
+	})
+
+	t.Run("emits a go-source meta tag with the configured templates", func(t *testing.T) {
+		h := httph.GoGet(httph.GoGetOptions{
+			Domain:       "maragu.dev",
+			Modules:      []string{"httph"},
+			URLPrefix:    "https://gitlab.com/maragudk",
+			DirTemplate:  "/-/tree/main{/dir}",
+			FileTemplate: "/-/blob/main{/dir}/{file}#L{line}",
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/httph?go-get=1", nil)
+		res := httptest.NewRecorder()
+		h(http.NotFoundHandler()).ServeHTTP(res, req)
+
+		is.Equal(t, `<meta name="go-import" content="maragu.dev/httph git https://gitlab.com/maragudk/httph">
+<meta name="go-source" content="maragu.dev/httph
+  https://gitlab.com/maragudk/httph
+  https://gitlab.com/maragudk/httph/-/tree/main{/dir}
+  https://gitlab.com/maragudk/httph/-/blob/main{/dir}/{file}#L{line}">
+`, res.Body.String())
+	})
+
+	t.Run("omits the go-source meta tag without templates for other hosts than GitHub", func(t *testing.T) {
+		h := httph.GoGet(httph.GoGetOptions{
+			Domain:    "maragu.dev",
+			Modules:   []string{"httph"},
+			URLPrefix: "https://gitlab.com/maragudk",
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/httph?go-get=1", nil)
+		res := httptest.NewRecorder()
+		h(http.NotFoundHandler()).ServeHTTP(res, req)
+
+		is.Equal(t, `<meta name="go-import" content="maragu.dev/httph git https://gitlab.com/maragudk/httph">
+`, res.Body.String())
+	})
+
+	t.Run("passes through paths not matching a pattern", func(t *testing.T) {
+		h := httph.GoGet(httph.GoGetOptions{
+			Domain:    "maragu.dev",
+			Modules:   []string{"tools/*"},
+			URLPrefix: "https://github.com/maragudk",
+		})
+
+		for _, path := range []string{"/tools?go-get=1", "/tools/?go-get=1", "/other/gen?go-get=1"} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			res := httptest.NewRecorder()
+			h(http.NotFoundHandler()).ServeHTTP(res, req)
+
+			is.Equal(t, http.StatusNotFound, res.Result().StatusCode)
+		}
+	})
+
 	t.Run("redirects valid modules to the URL prefix when no go-get parameter is supplied", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/httph", nil)
 		res := httptest.NewRecorder()
@@ -438,5 +2564,209 @@ func TestVersionedAssets(t *testing.T) {
 
 		is.Equal(t, http.StatusOK, res.Result().StatusCode)
 		is.Equal(t, "/script.js", req.URL.Path)
+		is.Equal(t, "", res.Result().Header.Get("Cache-Control"))
+	})
+
+	t.Run("sets long-lived cache headers for versioned assets", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/script.1a2b3c4d.js", nil)
+		res := httptest.NewRecorder()
+
+		h := httph.VersionedAssets(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("alert(1)"))
+		}))
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, "public, max-age=31536000, immutable", res.Result().Header.Get("Cache-Control"))
+	})
+
+	t.Run("does not set cache headers for versions that don't look like a hash", func(t *testing.T) {
+		for _, p := range []string{"/app.min.js", "/script.123456.js"} {
+			req := httptest.NewRequest(http.MethodGet, p, nil)
+			res := httptest.NewRecorder()
+
+			h := httph.VersionedAssets(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("alert(1)"))
+			}))
+			h.ServeHTTP(res, req)
+
+			is.Equal(t, http.StatusOK, res.Result().StatusCode)
+			is.Equal(t, "", res.Result().Header.Get("Cache-Control"))
+		}
+	})
+
+	t.Run("does not set cache headers for errors", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/script.1a2b3c4d.js", nil)
+		res := httptest.NewRecorder()
+
+		h := httph.VersionedAssets(http.NotFoundHandler())
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusNotFound, res.Result().StatusCode)
+		is.Equal(t, "", res.Result().Header.Get("Cache-Control"))
+	})
+
+	t.Run("can configure the cache headers", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/script.1a2b3c4d.js", nil)
+		res := httptest.NewRecorder()
+
+		h := httph.VersionedAssetsWithOptions(httph.VersionedAssetsOptions{MaxAge: time.Hour, DisableImmutable: true})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, "/script.js", req.URL.Path)
+		is.Equal(t, "public, max-age=3600", res.Result().Header.Get("Cache-Control"))
+	})
+
+	t.Run("can use a custom matcher", func(t *testing.T) {
+		h := httph.VersionedAssetsWithOptions(httph.VersionedAssetsOptions{
+			Matcher: regexp.MustCompile(`^(?P<name>.+)\.[A-Fa-f0-9]{8}(?P<extension>\.[a-z0-9]+)$`),
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		req := httptest.NewRequest(http.MethodGet, "/app.min.ABCD1234.js", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, "/app.min.js", req.URL.Path)
+		is.Equal(t, "public, max-age=31536000, immutable", res.Result().Header.Get("Cache-Control"))
+
+		req = httptest.NewRequest(http.MethodGet, "/script.123456.js", nil)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		is.Equal(t, "/script.123456.js", req.URL.Path)
+	})
+
+	t.Run("panics on a matcher without named groups", func(t *testing.T) {
+		defer func() {
+			is.True(t, recover() != nil)
+		}()
+		httph.VersionedAssetsWithOptions(httph.VersionedAssetsOptions{Matcher: regexp.MustCompile(`^(.+)\.v1(\.js)$`)})
+	})
+}
+
+func TestVersionedPath(t *testing.T) {
+	fsys := fstest.MapFS{
+		"js/app.js":  {Data: []byte("alert(1)")},
+		"app.min.js": {Data: []byte("alert(1)")},
+	}
+
+	t.Run("returns a versioned path that VersionedAssets maps back to the original", func(t *testing.T) {
+		p, err := httph.VersionedPath(fsys, "js/app.js")
+		is.NotError(t, err)
+		is.True(t, regexp.MustCompile(`^js/app\.[0-9a-f]{12}\.js$`).MatchString(p))
+
+		var path string
+		h := httph.VersionedAssets(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+		}))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/"+p, nil))
+		is.Equal(t, "/js/app.js", path)
+	})
+
+	t.Run("changes with the content", func(t *testing.T) {
+		p1, err := httph.VersionedPath(fsys, "js/app.js")
+		is.NotError(t, err)
+		p2, err := httph.VersionedPath(fstest.MapFS{"js/app.js": {Data: []byte("alert(2)")}}, "js/app.js")
+		is.NotError(t, err)
+		is.True(t, p1 != p2)
+	})
+
+	t.Run("errors on missing files and names that can't be versioned", func(t *testing.T) {
+		_, err := httph.VersionedPath(fsys, "nope.js")
+		is.True(t, errors.Is(err, fs.ErrNotExist))
+
+		_, err = httph.VersionedPath(fsys, "app.min.js")
+		is.True(t, err != nil)
+	})
+}
+
+func TestHTTPError_Error(t *testing.T) {
+	t.Run("includes the code, status text, and message", func(t *testing.T) {
+		err := httph.HTTPError{Code: http.StatusTooManyRequests, Message: "your quota is exhausted"}
+		is.Equal(t, "429 Too Many Requests: your quota is exhausted", err.Error())
+	})
+
+	t.Run("includes only the code and status text without a message", func(t *testing.T) {
+		err := httph.HTTPError{Code: http.StatusNotFound}
+		is.Equal(t, "404 Not Found", err.Error())
+	})
+	t.Run("includes the underlying error", func(t *testing.T) {
+		err := httph.NewHTTPError(http.StatusBadRequest, errors.New("invalid name"))
+		is.Equal(t, "400 Bad Request: invalid name", err.Error())
+	})
+}
+
+func TestNewHTTPError(t *testing.T) {
+	t.Run("wraps the error for errors.Is and errors.As", func(t *testing.T) {
+		cause := &fs.PathError{Op: "open", Path: "cat.txt", Err: fs.ErrNotExist}
+		err := fmt.Errorf("loading: %w", httph.NewHTTPError(http.StatusNotFound, cause))
+
+		is.True(t, errors.Is(err, fs.ErrNotExist))
+
+		var pathErr *fs.PathError
+		is.True(t, errors.As(err, &pathErr))
+		is.Equal(t, "cat.txt", pathErr.Path)
+
+		var httpErr httph.HTTPError
+		is.True(t, errors.As(err, &httpErr))
+		is.Equal(t, http.StatusNotFound, httpErr.StatusCode())
+	})
+
+	t.Run("does not show the wrapped error to clients", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
+			return nil, httph.NewHTTPError(http.StatusBadRequest, errors.New("secret database details"))
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"Bad Request"}`, readBody(t, res))
+	})
+
+	t.Run("does not show the wrapped error to clients for a pointer", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
+			return nil, &httph.HTTPError{Code: http.StatusBadRequest, Err: errors.New("secret database details")}
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"Bad Request"}`, readBody(t, res))
+	})
+
+	t.Run("does not show the wrapped error to clients when wrapped itself", func(t *testing.T) {
+		h := httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
+			err := httph.NewHTTPError(http.StatusNotFound, errors.New("secret database details"))
+			return nil, fmt.Errorf("loading: %w", err)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusNotFound, res.Result().StatusCode)
+		is.Equal(t, `{"Error":"Not Found"}`, readBody(t, res))
+	})
+
+	t.Run("does not show the wrapped error to clients when a wrapped pointer", func(t *testing.T) {
+		h := httph.ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+			err := &httph.HTTPError{Code: http.StatusConflict, Err: errors.New("secret database details")}
+			return fmt.Errorf("saving: %w", err)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", "application/json")
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusConflict, res.Result().StatusCode)
+		is.True(t, !strings.Contains(readBody(t, res), "secret"))
 	})
 }
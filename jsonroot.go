@@ -0,0 +1,65 @@
+package httph
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// RequireJSONRoot is Middleware to require the JSON request body to have an object or array at the root,
+// given by delim as json.Delim('{') or json.Delim('['). Other bodies return http.StatusBadRequest with a clear message,
+// before the next handler decodes them.
+// Only the first token is read, and the body is restored for the next handler, so it works alongside JSONHandler.
+// Requests without a body are passed through.
+func RequireJSONRoot(delim json.Delim) Middleware {
+	var name string
+	switch delim {
+	case '{':
+		name = "an object"
+	case '[':
+		name = "an array"
+	default:
+		panic("invalid JSON root delimiter " + delim.String())
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var read bytes.Buffer
+			dec := json.NewDecoder(io.TeeReader(r.Body, &read))
+			token, err := dec.Token()
+			if errors.Is(err, io.EOF) {
+				r.Body = replayBody{Reader: &read, body: r.Body}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil {
+				http.Error(w, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if token != delim {
+				http.Error(w, "JSON body must be "+name, http.StatusBadRequest)
+				return
+			}
+
+			r.Body = replayBody{Reader: io.MultiReader(&read, r.Body), body: r.Body}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// replayBody reads from Reader, and closes the original body.
+type replayBody struct {
+	io.Reader
+	body io.Closer
+}
+
+func (b replayBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,78 @@
+package httph_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestRequireJSONRoot(t *testing.T) {
+	type req struct {
+		Name string
+	}
+
+	h := httph.RequireJSONRoot('{')(httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, req req) (string, error) {
+		return req.Name, nil
+	}))
+
+	t.Run("passes an object through to the next handler", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(` {"Name":"Me"}`))
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, r)
+
+		is.Equal(t, http.StatusOK, res.Code)
+		is.Equal(t, `"Me"`, readBody(t, res))
+	})
+
+	t.Run("returns bad request for other root types", func(t *testing.T) {
+		for _, body := range []string{`[{"Name":"Me"}]`, `"Me"`, `1`, `null`} {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, r)
+
+			is.Equal(t, http.StatusBadRequest, res.Code)
+			is.Equal(t, "JSON body must be an object", readBody(t, res))
+		}
+	})
+
+	t.Run("returns bad request for invalid JSON", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`}`))
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, r)
+
+		is.Equal(t, http.StatusBadRequest, res.Code)
+		is.True(t, strings.HasPrefix(readBody(t, res), "invalid JSON body: "))
+	})
+
+	t.Run("passes through requests without a body", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(""))
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, r)
+
+		is.Equal(t, http.StatusOK, res.Code)
+	})
+
+	t.Run("can require an array", func(t *testing.T) {
+		h := httph.RequireJSONRoot(json.Delim('['))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, r)
+
+		is.Equal(t, http.StatusBadRequest, res.Code)
+		is.Equal(t, "JSON body must be an array", readBody(t, res))
+	})
+
+	t.Run("panics on invalid delimiter", func(t *testing.T) {
+		defer func() {
+			is.True(t, recover() != nil)
+		}()
+		httph.RequireJSONRoot('}')
+	})
+}
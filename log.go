@@ -0,0 +1,55 @@
+package httph
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// LogOptions for the Log Middleware.
+type LogOptions struct {
+	// Logger to log to. Defaults to slog.Default.
+	Logger *slog.Logger
+
+	// Skip logging requests for which Skip returns true, like health checks. Nil logs all requests.
+	Skip func(r *http.Request) bool
+}
+
+// Log is Middleware to log each request after it's handled, with the method, path, status code, duration,
+// and number of bytes written to the response body, at the info level.
+// If StartTime is earlier in the chain, the duration is measured from its start time.
+// The response writer passed to the next handler is a ResponseWriterWrapper.
+func Log(opts LogOptions) Middleware {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if opts.Skip != nil && opts.Skip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start, ok := StartTimeFromContext(r.Context())
+			if !ok {
+				start = time.Now()
+			}
+
+			ww := WrapResponseWriter(w)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			opts.Logger.LogAttrs(r.Context(), slog.LevelInfo, "HTTP request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Duration("duration", time.Since(start)),
+				slog.Int64("bytes", ww.BytesWritten()),
+			)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package httph_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestLog(t *testing.T) {
+	newLogger := func() (*slog.Logger, *bytes.Buffer) {
+		var b bytes.Buffer
+		return slog.New(slog.NewTextHandler(&b, &slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey || a.Key == "duration" {
+					return slog.Attr{}
+				}
+				return a
+			},
+		})), &b
+	}
+
+	t.Run("logs the method, path, status code, and bytes written", func(t *testing.T) {
+		log, b := newLogger()
+		h := httph.Log(httph.LogOptions{Logger: log})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("Hello"))
+			_, _ = w.Write([]byte(", world"))
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/users?x=1", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusCreated, res.Code)
+		is.Equal(t, `level=INFO msg="HTTP request" method=POST path=/users status=201 bytes=12`+"\n", b.String())
+	})
+
+	t.Run("logs an implicit OK status", func(t *testing.T) {
+		log, b := newLogger()
+		h := httph.Log(httph.LogOptions{Logger: log})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		is.True(t, strings.Contains(b.String(), "status=200 bytes=0"))
+	})
+
+	t.Run("skips requests", func(t *testing.T) {
+		log, b := newLogger()
+		h := httph.Log(httph.LogOptions{Logger: log, Skip: func(r *http.Request) bool {
+			return r.URL.Path == "/health"
+		}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		is.Equal(t, "", b.String())
+	})
+
+	t.Run("preserves flushing", func(t *testing.T) {
+		log, _ := newLogger()
+		h := httph.Log(httph.LogOptions{Logger: log})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.(http.Flusher).Flush()
+		}))
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		is.True(t, res.Flushed)
+	})
+}
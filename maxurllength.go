@@ -0,0 +1,30 @@
+package httph
+
+import (
+	"net/http"
+)
+
+// MaxURLLength is Middleware to return http.StatusRequestURITooLong when the request URI,
+// that is, the path and query, is longer than n bytes.
+// If n is zero or less, the default of 8192 bytes is used, which is also a common limit in proxies and CDNs.
+func MaxURLLength(n int) Middleware {
+	if n <= 0 {
+		n = 8192
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			uri := r.RequestURI
+			if uri == "" {
+				uri = r.URL.RequestURI()
+			}
+
+			if len(uri) > n {
+				http.Error(w, http.StatusText(http.StatusRequestURITooLong), http.StatusRequestURITooLong)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
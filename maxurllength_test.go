@@ -0,0 +1,46 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestMaxURLLength(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	t.Run("allows a request URI up to the limit", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/abc?d=e", nil)
+		res := httptest.NewRecorder()
+
+		httph.MaxURLLength(8)(next).ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+	})
+
+	t.Run("returns URI too long when the path and query exceed the limit", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/abc?d=ef", nil)
+		res := httptest.NewRecorder()
+
+		httph.MaxURLLength(8)(next).ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusRequestURITooLong, res.Result().StatusCode)
+	})
+
+	t.Run("uses a default limit", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/"+strings.Repeat("a", 8191), nil)
+		res := httptest.NewRecorder()
+		httph.MaxURLLength(0)(next).ServeHTTP(res, req)
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+
+		req = httptest.NewRequest(http.MethodGet, "/"+strings.Repeat("a", 8192), nil)
+		res = httptest.NewRecorder()
+		httph.MaxURLLength(0)(next).ServeHTTP(res, req)
+		is.Equal(t, http.StatusRequestURITooLong, res.Result().StatusCode)
+	})
+}
@@ -0,0 +1,60 @@
+package httph
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+)
+
+// MinTLSOptions for the MinTLS Middleware.
+type MinTLSOptions struct {
+	// StatusCode for rejected requests. Defaults to http.StatusForbidden.
+	StatusCode int
+
+	// OnReject is called for each rejected request, for example to log it. Optional.
+	OnReject func(r *http.Request)
+}
+
+// MinTLS is Middleware to reject requests that arrived over a TLS version below version, like tls.VersionTLS12.
+// The server can enforce this for all connections with tls.Config.MinVersion, but this allows a policy per route,
+// and a clear message to the client instead of a failed handshake.
+// Rejected requests return http.StatusForbidden, or the status code given in the options.
+// Note that requests without TLS information in http.Request.TLS are passed through, which is the case
+// both for plain HTTP and behind a proxy terminating TLS, where the proxy needs to enforce the version instead.
+func MinTLS(version uint16, optsFuncs ...func(opts *MinTLSOptions)) Middleware {
+	opts := &MinTLSOptions{
+		StatusCode: http.StatusForbidden,
+	}
+	for _, f := range optsFuncs {
+		f(opts)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS == nil || r.TLS.Version >= version {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if opts.OnReject != nil {
+				opts.OnReject(r)
+			}
+			http.Error(w, tlsVersionName(r.TLS.Version)+" is not allowed, minimum is "+tlsVersionName(version), opts.StatusCode)
+		})
+	}
+}
+
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("TLS version 0x%04X", version)
+	}
+}
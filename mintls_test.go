@@ -0,0 +1,69 @@
+package httph_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestMinTLS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	t.Run("rejects connections below the minimum version", func(t *testing.T) {
+		h := httph.MinTLS(tls.VersionTLS12)(next)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.TLS = &tls.ConnectionState{Version: tls.VersionTLS11}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusForbidden, res.Result().StatusCode)
+		is.Equal(t, "TLS 1.1 is not allowed, minimum is TLS 1.2", readBody(t, res))
+	})
+
+	t.Run("allows connections at or above the minimum version", func(t *testing.T) {
+		h := httph.MinTLS(tls.VersionTLS12)(next)
+
+		for _, version := range []uint16{tls.VersionTLS12, tls.VersionTLS13} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.TLS = &tls.ConnectionState{Version: version}
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+
+			is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		}
+	})
+
+	t.Run("passes through requests without TLS information", func(t *testing.T) {
+		h := httph.MinTLS(tls.VersionTLS13)(next)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+	})
+
+	t.Run("uses the status code from the options and calls OnReject", func(t *testing.T) {
+		var rejected bool
+		h := httph.MinTLS(tls.VersionTLS13, func(opts *httph.MinTLSOptions) {
+			opts.StatusCode = http.StatusUpgradeRequired
+			opts.OnReject = func(r *http.Request) {
+				rejected = true
+			}
+		})(next)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.TLS = &tls.ConnectionState{Version: tls.VersionTLS12}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusUpgradeRequired, res.Result().StatusCode)
+		is.True(t, rejected)
+	})
+}
@@ -0,0 +1,232 @@
+package httph
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeaderSliceType = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+// fileField is a struct field for a multipart file, declared with a tag like `form:"avatar,maxsize=1MB"`.
+type fileField struct {
+	index    int
+	name     string
+	multiple bool
+	tagged   bool
+	maxSize  int64
+	maxTag   string
+}
+
+// fileFields of the struct type t, which are the fields of type *multipart.FileHeader or []*multipart.FileHeader.
+// The form field name is the first part of the form tag, and defaults to the struct field name, matched case-insensitively.
+// Panics on invalid maxsize tag options, so mistakes are caught when creating the handler.
+func fileFields(t reflect.Type) []fileField {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []fileField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Type != fileHeaderType && sf.Type != fileHeaderSliceType {
+			continue
+		}
+
+		f := fileField{index: i, name: sf.Name, multiple: sf.Type == fileHeaderSliceType}
+		name, options, _ := strings.Cut(sf.Tag.Get("form"), ",")
+		if name != "" {
+			f.name = name
+			f.tagged = true
+		}
+		for _, option := range strings.Split(options, ",") {
+			if v, ok := strings.CutPrefix(option, "maxsize="); ok {
+				size, err := parseSize(v)
+				if err != nil {
+					panic(fmt.Sprintf("invalid maxsize for form field %v: %v", f.name, err))
+				}
+				f.maxSize = size
+				f.maxTag = v
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// parseSize parses human-readable sizes like "512", "100B", "1KB", "1.5MB", and "2GiB".
+// KB, MB, and GB are powers of 1000, and KiB, MiB, and GiB are powers of 1024. Units are case-insensitive.
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	number, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	multipliers := map[string]float64{
+		"": 1, "b": 1,
+		"kb": 1e3, "mb": 1e6, "gb": 1e9,
+		"kib": 1 << 10, "mib": 1 << 20, "gib": 1 << 30,
+	}
+	multiplier, ok := multipliers[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit in %q", s)
+	}
+	return int64(n * multiplier), nil
+}
+
+// isMultipartForm returns whether the request has a multipart/form-data body.
+func isMultipartForm(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// fileTooLargeError is returned from parseMultipartForm when a file exceeds its field's maxsize.
+type fileTooLargeError struct {
+	field fileField
+}
+
+func (e fileTooLargeError) Error() string {
+	return fmt.Sprintf("file %v is too large, max is %v", e.field.name, e.field.maxTag)
+}
+
+// parseMultipartForm like http.Request.ParseMultipartForm, except that file parts are limited to the maxsize of
+// their fields while streaming, so a too large file is never read in full.
+// This works by checking each part while copying the body to a new multipart stream, which is then read as usual.
+// Call http.Request.ParseForm first.
+func parseMultipartForm(r *http.Request, maxMemory int64, fields []fileField) error {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	copyErr := make(chan error, 1)
+	go func() {
+		err := copyParts(mr, mw, fields)
+		copyErr <- err
+		_ = pw.CloseWithError(err)
+	}()
+
+	form, err := multipart.NewReader(pr, mw.Boundary()).ReadForm(maxMemory)
+	if err != nil {
+		// Unblock the copying, and prefer its error, which is the cause if a file was too large
+		_ = pr.CloseWithError(err)
+		var tooLarge fileTooLargeError
+		if errors.As(<-copyErr, &tooLarge) {
+			return tooLarge
+		}
+		return err
+	}
+	_, _ = io.Copy(io.Discard, pr)
+	if err := <-copyErr; err != nil {
+		_ = form.RemoveAll()
+		return err
+	}
+
+	for k, vs := range form.Value {
+		r.Form[k] = append(r.Form[k], vs...)
+		if r.PostForm == nil {
+			r.PostForm = map[string][]string{}
+		}
+		r.PostForm[k] = append(r.PostForm[k], vs...)
+	}
+	r.MultipartForm = form
+	return nil
+}
+
+// copyParts from mr to mw, checking file sizes against the maxsize of their fields.
+func copyParts(mr *multipart.Reader, mw *multipart.Writer, fields []fileField) error {
+	var limits []fileField
+	for _, f := range fields {
+		if f.maxSize > 0 {
+			limits = append(limits, f)
+		}
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return mw.Close()
+		}
+		if err != nil {
+			return err
+		}
+
+		w, err := mw.CreatePart(part.Header)
+		if err != nil {
+			return err
+		}
+
+		f, limited := fileFieldLimit(limits, part.FormName())
+		if !limited || part.FileName() == "" {
+			if _, err := io.Copy(w, part); err != nil {
+				return err
+			}
+			continue
+		}
+
+		n, err := io.Copy(w, io.LimitReader(part, f.maxSize+1))
+		if err != nil {
+			return err
+		}
+		if n > f.maxSize {
+			return fileTooLargeError{field: f}
+		}
+	}
+}
+
+// fileFieldLimit for the form field name from limits, matched like in setFileFields,
+// so case-insensitively for untagged fields.
+func fileFieldLimit(limits []fileField, name string) (fileField, bool) {
+	for _, f := range limits {
+		if f.name == name || (!f.tagged && strings.EqualFold(f.name, name)) {
+			return f, true
+		}
+	}
+	return fileField{}, false
+}
+
+// setFileFields on the struct pointed to by v from the multipart form files.
+func setFileFields(v reflect.Value, form *multipart.Form, fields []fileField) {
+	if form == nil {
+		return
+	}
+	for _, f := range fields {
+		fhs := form.File[f.name]
+		if !f.tagged && len(fhs) == 0 {
+			for k := range form.File {
+				if strings.EqualFold(k, f.name) {
+					fhs = form.File[k]
+					break
+				}
+			}
+		}
+		if len(fhs) == 0 {
+			continue
+		}
+		if f.multiple {
+			v.Elem().Field(f.index).Set(reflect.ValueOf(fhs))
+			continue
+		}
+		v.Elem().Field(f.index).Set(reflect.ValueOf(fhs[0]))
+	}
+}
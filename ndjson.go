@@ -0,0 +1,55 @@
+package httph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// NDJSONWriter writes newline-delimited JSON to a response, one JSON value per line, flushing after each.
+// See https://github.com/ndjson/ndjson-spec
+type NDJSONWriter struct {
+	w       http.ResponseWriter
+	ctx     context.Context
+	rc      *http.ResponseController
+	enc     *json.Encoder
+	started bool
+}
+
+// NewNDJSONWriter for the response to r. The Content-Type header is set to application/x-ndjson on the first Encode,
+// unless it's already set.
+// Note that the status code and headers are sent with the first value, so they can't be changed after that.
+// If you need another status code than http.StatusOK, call http.ResponseWriter.WriteHeader before the first Encode.
+func NewNDJSONWriter(w http.ResponseWriter, r *http.Request) *NDJSONWriter {
+	return &NDJSONWriter{
+		w:   w,
+		ctx: r.Context(),
+		rc:  http.NewResponseController(w),
+		enc: json.NewEncoder(w),
+	}
+}
+
+// Encode v as JSON on its own line, and flush it to the client.
+// If the client has disconnected, the request context error is returned, so the stream can stop.
+func (n *NDJSONWriter) Encode(v any) error {
+	if err := n.ctx.Err(); err != nil {
+		return err
+	}
+
+	if !n.started {
+		n.started = true
+		if n.w.Header().Get("Content-Type") == "" {
+			n.w.Header().Set("Content-Type", "application/x-ndjson")
+		}
+	}
+
+	if err := n.enc.Encode(v); err != nil {
+		return err
+	}
+
+	if err := n.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
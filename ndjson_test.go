@@ -0,0 +1,40 @@
+package httph_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestNDJSONWriter(t *testing.T) {
+	t.Run("writes a JSON value per line and flushes", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		nw := httph.NewNDJSONWriter(res, req)
+		is.NotError(t, nw.Encode(map[string]int{"a": 1}))
+		is.True(t, res.Flushed)
+		is.NotError(t, nw.Encode(map[string]int{"b": 2}))
+
+		is.Equal(t, "application/x-ndjson", res.Header().Get("Content-Type"))
+		is.Equal(t, "{\"a\":1}\n{\"b\":2}\n", res.Body.String())
+	})
+
+	t.Run("stops when the client disconnects", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		res := httptest.NewRecorder()
+
+		nw := httph.NewNDJSONWriter(res, req)
+		is.NotError(t, nw.Encode(1))
+		cancel()
+
+		is.Equal(t, context.Canceled, nw.Encode(2))
+		is.Equal(t, "1\n", res.Body.String())
+	})
+}
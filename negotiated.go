@@ -0,0 +1,152 @@
+package httph
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+)
+
+// NegotiatedHandler is like JSONHandler, except it also speaks XML.
+// The request body is decoded as XML if the Content-Type is application/xml or text/xml, and as JSON otherwise.
+// The response is encoded as XML if the Accept header prefers application/xml over application/json,
+// and as JSON otherwise, and the Content-Type is set accordingly. Error responses follow the same format,
+// in XML like <ErrorResponse><Error>...</Error></ErrorResponse>.
+// The validator, contextValidator, maxSizeGiver, statusCodeGiver, contentTypeGiver, and headerGiver interfaces work like in JSONHandler.
+// The request body is limited to 1 MiB by default, like in JSONHandler.
+// The jsonStreamer interface works like in JSONHandler for JSON responses, and is ignored for XML responses.
+func NegotiatedHandler[Req any, Res any](h func(http.ResponseWriter, *http.Request, Req) (Res, error)) http.HandlerFunc {
+	opts := &JSONHandlerOptions{
+		MaxSizeBytes: 1 << 20,
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Req
+
+		asXML := negotiate(r.Header.Get("Accept"), "application/json", "application/xml") == "application/xml"
+		writeError := func(code int, res errorResponse) {
+			if asXML {
+				writeXMLErrorResponse(w, code, res)
+				return
+			}
+			// There's no ErrorResponse option here, so the error itself isn't needed
+			writeErrorResponse(w, r, opts, code, nil, res)
+		}
+
+		limitBody(w, r, opts, req)
+
+		// Try reading a request body, skip if there is none
+		br := getBufioReader(r.Body)
+		defer putBufioReader(br)
+		if _, err := br.Peek(1); err == nil {
+			var decodeErr error
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if mediaType == "application/xml" || mediaType == "text/xml" {
+				if err := xml.NewDecoder(br).Decode(&req); err != nil {
+					decodeErr = fmt.Errorf("error decoding request body as XML: %w", err)
+				}
+			} else if err := json.NewDecoder(br).Decode(&req); err != nil {
+				decodeErr = fmt.Errorf("error decoding request body as JSON: %w", err)
+			}
+			if message, ok := bodyTooLargeMessage(decodeErr); ok {
+				writeError(http.StatusRequestEntityTooLarge, errorResponse{Error: message})
+				return
+			}
+			if decodeErr != nil {
+				writeError(http.StatusBadRequest, errorResponse{Error: decodeErr.Error()})
+				return
+			}
+		}
+
+		if err := validate(r.Context(), req); err != nil {
+			writeError(http.StatusBadRequest, validationErrorResponse(err))
+			return
+		}
+
+		res, err := h(w, r, req)
+		if !asXML {
+			writeJSONResult(w, r, opts, "", res, err)
+			return
+		}
+
+		if err != nil {
+			code := errorStatusCode(err)
+			logError(r, code, err)
+			writeError(code, errorResponseFor(err))
+			return
+		}
+
+		code := responseStatusCode(res)
+		if code == http.StatusNoContent {
+			setResponseHeaders(w, res)
+			w.WriteHeader(code)
+			return
+		}
+
+		b := getBuffer()
+		defer putBuffer(b)
+		if err := xml.NewEncoder(b).Encode(res); err != nil {
+			writeError(http.StatusInternalServerError, errorResponse{
+				Error: fmt.Errorf("error encoding response body as XML: %w", err).Error(),
+			})
+			return
+		}
+
+		setResponseHeaders(w, res)
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		if res, ok := any(res).(contentTypeGiver); ok {
+			w.Header().Set("Content-Type", res.ContentType())
+		}
+
+		w.WriteHeader(code)
+
+		// There's not much we can do about an error here, so ignore it
+		_, _ = io.Copy(w, b)
+	}
+}
+
+// xmlErrorResponse is errorResponse for XML, which can't encode maps.
+type xmlErrorResponse struct {
+	XMLName xml.Name   `xml:"ErrorResponse"`
+	Error   string     `xml:"Error"`
+	Errors  *xmlErrors `xml:",omitempty"`
+	Fields  *xmlFields `xml:",omitempty"`
+}
+
+type xmlErrors struct {
+	Errors []string `xml:"Error"`
+}
+
+type xmlFields struct {
+	Fields []xmlField `xml:"Field"`
+}
+
+type xmlField struct {
+	Name    string `xml:"name,attr"`
+	Message string `xml:",chardata"`
+}
+
+// writeXMLErrorResponse with the status code, with fields sorted by name.
+func writeXMLErrorResponse(w http.ResponseWriter, code int, res errorResponse) {
+	xres := xmlErrorResponse{Error: res.Error}
+	if len(res.Errors) > 0 {
+		xres.Errors = &xmlErrors{Errors: res.Errors}
+	}
+	if len(res.Fields) > 0 {
+		xres.Fields = &xmlFields{}
+		for name, message := range res.Fields {
+			xres.Fields.Fields = append(xres.Fields.Fields, xmlField{Name: name, Message: message})
+		}
+		sort.Slice(xres.Fields.Fields, func(i, j int) bool {
+			return xres.Fields.Fields[i].Name < xres.Fields.Fields[j].Name
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(code)
+	// If there's an error here, it's probably an error writing to the client that we can't do anything about, so ignore it.
+	_ = xml.NewEncoder(w).Encode(xres)
+}
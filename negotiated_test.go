@@ -0,0 +1,105 @@
+package httph_test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+type negotiatedReq struct {
+	XMLName xml.Name `xml:"Greeting" json:"-"`
+	Name    string
+}
+
+type negotiatedRes struct {
+	XMLName xml.Name `xml:"Reply" json:"-"`
+	Message string
+}
+
+func TestNegotiatedHandler(t *testing.T) {
+	h := httph.NegotiatedHandler(func(w http.ResponseWriter, r *http.Request, req negotiatedReq) (negotiatedRes, error) {
+		if req.Name == "" {
+			return negotiatedRes{}, httph.HTTPError{Code: http.StatusUnprocessableEntity}
+		}
+		return negotiatedRes{Message: "Hello " + req.Name}, nil
+	})
+
+	request := func(contentType, accept, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("decodes and encodes JSON by default", func(t *testing.T) {
+		res := request("application/json", "", `{"Name":"Me"}`)
+
+		is.Equal(t, http.StatusOK, res.Code)
+		is.Equal(t, "application/json", res.Header().Get("Content-Type"))
+		is.Equal(t, `{"Message":"Hello Me"}`, readBody(t, res))
+	})
+
+	t.Run("decodes and encodes XML when asked to", func(t *testing.T) {
+		res := request("application/xml", "application/xml", `<Greeting><Name>Me</Name></Greeting>`)
+
+		is.Equal(t, http.StatusOK, res.Code)
+		is.Equal(t, "application/xml; charset=utf-8", res.Header().Get("Content-Type"))
+		is.Equal(t, `<Reply><Message>Hello Me</Message></Reply>`, readBody(t, res))
+	})
+
+	t.Run("encodes XML for a JSON request preferring XML", func(t *testing.T) {
+		res := request("application/json", "application/json;q=0.5, application/xml", `{"Name":"Me"}`)
+
+		is.Equal(t, `<Reply><Message>Hello Me</Message></Reply>`, readBody(t, res))
+	})
+
+	t.Run("returns bad request in XML for a malformed XML body", func(t *testing.T) {
+		res := request("text/xml", "application/xml", `<Greeting><Name>Me</Greeting>`)
+
+		is.Equal(t, http.StatusBadRequest, res.Code)
+		is.Equal(t, "application/xml; charset=utf-8", res.Header().Get("Content-Type"))
+		is.True(t, strings.HasPrefix(readBody(t, res), `<ErrorResponse><Error>error decoding request body as XML: `))
+	})
+
+	t.Run("returns errors in XML with the status code from the error", func(t *testing.T) {
+		res := request("application/xml", "application/xml", `<Greeting></Greeting>`)
+
+		is.Equal(t, http.StatusUnprocessableEntity, res.Code)
+		is.Equal(t, `<ErrorResponse><Error>Unprocessable Entity</Error></ErrorResponse>`, readBody(t, res))
+	})
+
+	t.Run("returns field errors in XML sorted by name", func(t *testing.T) {
+		h := httph.NegotiatedHandler(func(w http.ResponseWriter, r *http.Request, req negotiatedReq) (negotiatedRes, error) {
+			var ve httph.ValidationError
+			ve.Add("Name", "is required")
+			ve.Add("Email", "is invalid")
+			return negotiatedRes{}, ve.Err()
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Accept", "application/xml")
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusBadRequest, res.Code)
+		is.Equal(t, `<ErrorResponse><Error>invalid request</Error><Fields><Field name="Email">is invalid</Field><Field name="Name">is required</Field></Fields></ErrorResponse>`, readBody(t, res))
+	})
+
+	t.Run("returns errors in JSON by default", func(t *testing.T) {
+		res := request("application/json", "", `{}`)
+
+		is.Equal(t, http.StatusUnprocessableEntity, res.Code)
+		is.Equal(t, "application/json", res.Header().Get("Content-Type"))
+		is.Equal(t, `{"Error":"Unprocessable Entity"}`, readBody(t, res))
+	})
+}
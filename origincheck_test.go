@@ -0,0 +1,118 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestOriginCheck(t *testing.T) {
+	h := httph.OriginCheck(httph.OriginCheckOptions{
+		Allowed: []string{"https://app.example.com", "https://*.example.org"},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name   string
+		origin string
+		code   int
+	}{
+		{"allows a listed origin", "https://app.example.com", http.StatusOK},
+		{"allows a wildcard subdomain", "https://ws.example.org", http.StatusOK},
+		{"allows same origin", "http://example.net", http.StatusOK},
+		{"forbids the apex of a wildcard", "https://example.org", http.StatusForbidden},
+		{"forbids a different scheme", "http://app.example.com", http.StatusForbidden},
+		{"forbids an unlisted origin", "https://evil.com", http.StatusForbidden},
+		{"forbids a suffix lookalike", "https://evilexample.org", http.StatusForbidden},
+		{"forbids the null origin", "null", http.StatusForbidden},
+		{"forbids a missing origin by default", "", http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://example.net/ws", nil)
+			if test.origin != "" {
+				req.Header.Set("Origin", test.origin)
+			}
+			res := httptest.NewRecorder()
+
+			h.ServeHTTP(res, req)
+
+			is.Equal(t, test.code, res.Result().StatusCode)
+		})
+	}
+
+	t.Run("allows a missing origin when configured", func(t *testing.T) {
+		h := httph.OriginCheck(httph.OriginCheckOptions{AllowMissing: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+	})
+
+	t.Run("only checks upgrade requests when configured", func(t *testing.T) {
+		h := httph.OriginCheck(httph.OriginCheckOptions{UpgradeOnly: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		req.Header.Set("Origin", "https://evil.com")
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+
+		req.Header.Set("Connection", "keep-alive, Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		res = httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		is.Equal(t, http.StatusForbidden, res.Result().StatusCode)
+	})
+
+	t.Run("compares hosts without ports when configured", func(t *testing.T) {
+		h := httph.OriginCheck(httph.OriginCheckOptions{
+			Allowed:    []string{"https://app.example.com"},
+			IgnorePort: true,
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		tests := []struct {
+			host, origin string
+			code         int
+		}{
+			{"example.com", "https://example.com:8443", http.StatusOK},
+			{"example.com:443", "https://example.com", http.StatusOK},
+			{"[::1]:8080", "http://[::1]", http.StatusOK},
+			{"example.com", "https://app.example.com:8443", http.StatusOK},
+			{"example.com", "https://evil.com:443", http.StatusForbidden},
+		}
+		for _, test := range tests {
+			req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+			req.Host = test.host
+			req.Header.Set("Origin", test.origin)
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+			is.Equal(t, test.code, res.Result().StatusCode)
+		}
+	})
+
+	t.Run("compares ports by default", func(t *testing.T) {
+		h := httph.OriginCheck(httph.OriginCheckOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		req.Host = "example.com"
+		req.Header.Set("Origin", "https://example.com:8443")
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		is.Equal(t, http.StatusForbidden, res.Result().StatusCode)
+	})
+
+	t.Run("panics on invalid allowed origin", func(t *testing.T) {
+		defer func() {
+			is.True(t, recover() != nil)
+		}()
+		httph.OriginCheck(httph.OriginCheckOptions{Allowed: []string{"example.com"}})
+	})
+}
@@ -0,0 +1,75 @@
+package httph
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PermissionsPolicyOptions for the PermissionsPolicy Middleware.
+type PermissionsPolicyOptions struct {
+	// Features maps feature names, like "geolocation", to allowlists.
+	// An allowlist entry is "self", "src", "*" on its own for any origin, or an origin like "https://example.com".
+	// An empty allowlist disables the feature.
+	// Defaults to disabling sensitive features like camera, geolocation, microphone, and payment.
+	Features map[string][]string
+}
+
+// permissionsPolicyFeatureMatcher matches valid feature names, like "geolocation" and "publickey-credentials-get".
+var permissionsPolicyFeatureMatcher = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// PermissionsPolicy is Middleware to set the Permissions-Policy header, to control which browser features
+// the page and embedded frames can use, like "geolocation=(), camera=(self)".
+// Features are rendered in sorted order. Panics on invalid feature names or allowlist entries.
+// See https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Permissions-Policy
+func PermissionsPolicy(opts PermissionsPolicyOptions) Middleware {
+	if opts.Features == nil {
+		opts.Features = map[string][]string{}
+		for _, feature := range []string{"accelerometer", "autoplay", "bluetooth", "camera", "display-capture",
+			"geolocation", "gyroscope", "hid", "magnetometer", "microphone", "midi", "payment", "serial", "usb"} {
+			opts.Features[feature] = nil
+		}
+	}
+
+	var features []string
+	for feature, allowlist := range opts.Features {
+		if !permissionsPolicyFeatureMatcher.MatchString(feature) {
+			panic("invalid permissions policy feature " + feature)
+		}
+		features = append(features, feature+"="+permissionsPolicyAllowlist(feature, allowlist))
+	}
+	sort.Strings(features)
+	value := strings.Join(features, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Permissions-Policy", value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// permissionsPolicyAllowlist renders the allowlist for feature, like "(self \"https://example.com\")".
+func permissionsPolicyAllowlist(feature string, allowlist []string) string {
+	if len(allowlist) == 1 && allowlist[0] == "*" {
+		return "*"
+	}
+
+	var entries []string
+	for _, entry := range allowlist {
+		switch entry {
+		case "self", "src":
+			entries = append(entries, entry)
+		default:
+			u, err := url.Parse(entry)
+			if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+				panic("invalid permissions policy allowlist entry for " + feature + ": " + entry)
+			}
+			entries = append(entries, strconv.Quote(u.Scheme+"://"+u.Host))
+		}
+	}
+	return "(" + strings.Join(entries, " ") + ")"
+}
@@ -0,0 +1,56 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestPermissionsPolicy(t *testing.T) {
+	request := func(opts httph.PermissionsPolicyOptions) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h := httph.PermissionsPolicy(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		h.ServeHTTP(res, req)
+		return res.Result().Header.Get("Permissions-Policy")
+	}
+
+	t.Run("disables sensitive features by default", func(t *testing.T) {
+		is.Equal(t, "accelerometer=(), autoplay=(), bluetooth=(), camera=(), display-capture=(), geolocation=(), "+
+			"gyroscope=(), hid=(), magnetometer=(), microphone=(), midi=(), payment=(), serial=(), usb=()",
+			request(httph.PermissionsPolicyOptions{}))
+	})
+
+	t.Run("renders custom allowlists per feature", func(t *testing.T) {
+		is.Equal(t, `camera=(self), fullscreen=*, geolocation=(), payment=(self "https://pay.example.com")`,
+			request(httph.PermissionsPolicyOptions{Features: map[string][]string{
+				"geolocation": {},
+				"camera":      {"self"},
+				"fullscreen":  {"*"},
+				"payment":     {"self", "https://pay.example.com"},
+			}}))
+	})
+
+	t.Run("panics on an invalid feature name", func(t *testing.T) {
+		defer func() {
+			is.Equal(t, any("invalid permissions policy feature Camera"), recover())
+		}()
+		httph.PermissionsPolicy(httph.PermissionsPolicyOptions{Features: map[string][]string{"Camera": {}}})
+	})
+
+	t.Run("panics on an invalid allowlist entry", func(t *testing.T) {
+		for _, entry := range []string{"'self'", "example.com", "https://example.com/path"} {
+			func() {
+				defer func() {
+					is.True(t, recover() != nil)
+				}()
+				httph.PermissionsPolicy(httph.PermissionsPolicyOptions{Features: map[string][]string{"camera": {entry}}})
+			}()
+		}
+	})
+}
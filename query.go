@@ -0,0 +1,58 @@
+package httph
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// QueryHandler is like FormHandler, except it only decodes the URL query parameters, for GET requests like
+// search and filter endpoints. Query parameter names are matched case-insensitively to struct field names,
+// or can be given with a query tag, like `query:"page_size"`.
+// Values are decoded weakly, like in FormHandler, so ints, bools, and time.Time fields work,
+// and repeated parameters decode into slices. Decoding errors result in http.StatusBadRequest.
+// Fields can have a default for when the parameter is absent, like `query:"page_size" default:"20"`,
+// decoded like a sent value. A parameter that is present, even if empty, like "?page_size=", doesn't get the default.
+// If the request struct satisfies the validator or contextValidator interface, also use it to validate the struct,
+// with errors rendered as plain text like in FormHandler, and http.StatusBadRequest.
+func QueryHandler[Req any](h func(http.ResponseWriter, *http.Request, Req)) http.HandlerFunc {
+	defaults := defaultFields(reflect.TypeOf((*Req)(nil)).Elem(), "query")
+	decoderConfig := mapstructure.DecoderConfig{
+		DecodeHook:       stringToTimeHook(nil),
+		MatchName:        strings.EqualFold,
+		TagName:          "query",
+		WeaklyTypedInput: true,
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Req
+
+		config := decoderConfig
+		config.Result = &req
+		dec, err := mapstructure.NewDecoder(&config)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		query := valuesMap(r.URL.Query())
+		setDefaults(query, defaults)
+		if err := dec.Decode(query); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := validate(r.Context(), req); err != nil {
+			if lines, ok := fieldErrorLines(err); ok {
+				http.Error(w, "invalid query:\n"+lines, http.StatusBadRequest)
+				return
+			}
+			http.Error(w, fmt.Sprintf("invalid query: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		h(w, r, req)
+	}
+}
@@ -0,0 +1,123 @@
+package httph_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+type searchReq struct {
+	Query    string `query:"q"`
+	Page     int
+	PageSize int `query:"page_size"`
+	Archived bool
+	Tags     []string `query:"tag"`
+}
+
+func (s searchReq) Validate() error {
+	if s.Page < 0 {
+		return errors.New("page must not be negative")
+	}
+	return nil
+}
+
+func TestQueryHandler(t *testing.T) {
+	t.Run("decodes query parameters into a struct", func(t *testing.T) {
+		var req searchReq
+		h := httph.QueryHandler(func(w http.ResponseWriter, r *http.Request, sr searchReq) {
+			req = sr
+		})
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/?q=hats&PAGE=2&page_size=20&archived=true&tag=a&tag=b", nil))
+
+		is.Equal(t, http.StatusOK, res.Code)
+		is.Equal(t, "hats", req.Query)
+		is.Equal(t, 2, req.Page)
+		is.Equal(t, 20, req.PageSize)
+		is.True(t, req.Archived)
+		is.Equal(t, 2, len(req.Tags))
+		is.Equal(t, "a", req.Tags[0])
+		is.Equal(t, "b", req.Tags[1])
+	})
+
+	t.Run("decodes a single repeated parameter into a slice", func(t *testing.T) {
+		var req searchReq
+		h := httph.QueryHandler(func(w http.ResponseWriter, r *http.Request, sr searchReq) {
+			req = sr
+		})
+
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?tag=a", nil))
+
+		is.Equal(t, 1, len(req.Tags))
+		is.Equal(t, "a", req.Tags[0])
+	})
+
+	t.Run("returns bad request on parse errors", func(t *testing.T) {
+		h := httph.QueryHandler(func(w http.ResponseWriter, r *http.Request, sr searchReq) {})
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/?page=first", nil))
+
+		is.Equal(t, http.StatusBadRequest, res.Code)
+	})
+
+	t.Run("returns bad request on validation errors", func(t *testing.T) {
+		var called bool
+		h := httph.QueryHandler(func(w http.ResponseWriter, r *http.Request, sr searchReq) {
+			called = true
+		})
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/?page=-1", nil))
+
+		is.Equal(t, http.StatusBadRequest, res.Code)
+		is.Equal(t, "invalid query: page must not be negative\n", res.Body.String())
+		is.True(t, !called)
+	})
+}
+
+func TestQueryHandler_defaults(t *testing.T) {
+	type pageReq struct {
+		Page     int    `default:"1"`
+		PageSize int    `query:"page_size" default:"20"`
+		Sort     string `default:"name"`
+	}
+
+	request := func(target string) pageReq {
+		var req pageReq
+		h := httph.QueryHandler(func(w http.ResponseWriter, r *http.Request, pr pageReq) {
+			req = pr
+		})
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, target, nil))
+		is.Equal(t, http.StatusOK, res.Code)
+		return req
+	}
+
+	t.Run("applies defaults for absent parameters", func(t *testing.T) {
+		req := request("/")
+		is.Equal(t, 1, req.Page)
+		is.Equal(t, 20, req.PageSize)
+		is.Equal(t, "name", req.Sort)
+	})
+
+	t.Run("does not apply defaults for sent parameters", func(t *testing.T) {
+		req := request("/?PAGE=3&page_size=50&sort=date")
+		is.Equal(t, 3, req.Page)
+		is.Equal(t, 50, req.PageSize)
+		is.Equal(t, "date", req.Sort)
+	})
+
+	t.Run("does not apply defaults for explicit zero and empty values", func(t *testing.T) {
+		req := request("/?page=0&page_size=&sort=")
+		is.Equal(t, 0, req.Page)
+		is.Equal(t, 0, req.PageSize)
+		is.Equal(t, "", req.Sort)
+	})
+}
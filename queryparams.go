@@ -0,0 +1,62 @@
+package httph
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// AllowQueryParams is Middleware to return http.StatusBadRequest for requests with query parameters
+// not in the allowlist of names, to reduce parameter pollution and keep cache keys stable.
+// Allowed parameters pass through unchanged. See StripQueryParams to remove unexpected parameters instead.
+func AllowQueryParams(names ...string) Middleware {
+	return queryParamsAllowlist(names, false)
+}
+
+// StripQueryParams is Middleware like AllowQueryParams, except it silently removes query parameters
+// not in the allowlist of names before calling the next handler.
+func StripQueryParams(names ...string) Middleware {
+	return queryParamsAllowlist(names, true)
+}
+
+func queryParamsAllowlist(names []string, strip bool) Middleware {
+	allowed := map[string]struct{}{}
+	for _, name := range names {
+		allowed[name] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.RawQuery == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			query := r.URL.Query()
+			var unexpected []string
+			for name := range query {
+				if _, ok := allowed[name]; !ok {
+					unexpected = append(unexpected, name)
+				}
+			}
+
+			if len(unexpected) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !strip {
+				sort.Strings(unexpected)
+				http.Error(w, "unexpected query parameters: "+strings.Join(unexpected, ", "), http.StatusBadRequest)
+				return
+			}
+
+			for _, name := range unexpected {
+				query.Del(name)
+			}
+			r.URL.RawQuery = query.Encode()
+			r.RequestURI = r.URL.RequestURI()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestAllowQueryParams(t *testing.T) {
+	h := httph.AllowQueryParams("q", "page")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.RawQuery))
+	}))
+
+	t.Run("passes through allowed parameters unchanged", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/?q=hat&page=2&q=goat", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "q=hat&page=2&q=goat", readBody(t, res))
+	})
+
+	t.Run("returns bad request on unexpected parameters", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/?q=hat&utm_source=x&cb=123", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusBadRequest, res.Result().StatusCode)
+		is.Equal(t, "unexpected query parameters: cb, utm_source", readBody(t, res))
+	})
+}
+
+func TestStripQueryParams(t *testing.T) {
+	h := httph.StripQueryParams("q", "page")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.RawQuery))
+	}))
+
+	t.Run("removes unexpected parameters", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/?q=hat&utm_source=x&page=2", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusOK, res.Result().StatusCode)
+		is.Equal(t, "page=2&q=hat", readBody(t, res))
+	})
+
+	t.Run("passes through allowed parameters unchanged", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/?q=hat&page=2", nil)
+		res := httptest.NewRecorder()
+
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, "q=hat&page=2", readBody(t, res))
+	})
+}
@@ -0,0 +1,311 @@
+package httph
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimitAlgorithm for the RateLimit Middleware.
+type RateLimitAlgorithm int
+
+const (
+	// TokenBucket allows bursts of up to RateLimitOptions.Burst requests,
+	// and then refills at a steady rate of Limit requests per Period. This is the default.
+	TokenBucket RateLimitAlgorithm = iota
+
+	// SlidingWindow allows up to Limit requests in any window of length Period,
+	// estimated from the counts in the current and previous fixed windows.
+	// Unlike TokenBucket, a client can use the whole Limit at once, but then has to wait for the window to slide.
+	SlidingWindow
+
+	// LeakyBucket drains requests at a steady rate of Limit requests per Period, delaying requests that arrive faster,
+	// so the next handler sees smooth traffic. Up to Burst requests can wait at a time, and more are rejected.
+	// Unlike TokenBucket, bursts are not passed on to the next handler, but spread out over time.
+	LeakyBucket
+)
+
+// RateLimitState is the state kept per key in a RateLimitStore.
+// Its meaning depends on the RateLimitAlgorithm, and it should be stored and returned as is.
+type RateLimitState struct {
+	Level float64
+	Count float64
+	Time  time.Time
+}
+
+// RateLimitStore keeps rate limiting state per key, for the RateLimit Middleware.
+type RateLimitStore interface {
+	// Update the state for key with f, which receives the current state, or the zero state if there is none,
+	// and returns the new state. Must be safe for concurrent use, and get and set atomically.
+	Update(ctx context.Context, key string, f func(state RateLimitState) RateLimitState) error
+}
+
+// RateLimitOptions for the RateLimit Middleware.
+type RateLimitOptions struct {
+	// Algorithm to use. Defaults to TokenBucket.
+	Algorithm RateLimitAlgorithm
+
+	// Limit of requests per Period. Required.
+	Limit int
+
+	// Period for Limit. Required.
+	Period time.Duration
+
+	// Burst is the bucket size for TokenBucket, and the number of requests that can wait for LeakyBucket.
+	// Not used for SlidingWindow. Defaults to Limit.
+	Burst int
+
+	// Key identifies the client. Defaults to the client IP from the RealIP Middleware if it's earlier in the chain,
+	// or else the host part of http.Request.RemoteAddr.
+	Key func(r *http.Request) string
+
+	// Store for the state per key. Defaults to a new MemoryRateLimitStore, which evicts idle keys
+	// once their state is the same as for a new key.
+	Store RateLimitStore
+
+	// Now is the clock used. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// RateLimit is Middleware to limit the request rate per client key, with the algorithm given in the options.
+// See TokenBucket, SlidingWindow, and LeakyBucket for the differences.
+// Requests over the limit return http.StatusTooManyRequests, with a Retry-After header set to when the client
+// may try again.
+func RateLimit(opts RateLimitOptions) Middleware {
+	if opts.Limit <= 0 || opts.Period <= 0 || opts.Burst < 0 {
+		panic("invalid rate limit options")
+	}
+	if opts.Burst == 0 {
+		opts.Burst = opts.Limit
+	}
+	if opts.Key == nil {
+		opts.Key = clientIP
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryRateLimitStore(func(storeOpts *MemoryRateLimitStoreOptions) {
+			storeOpts.IdleTimeout = opts.idleTimeout()
+			storeOpts.Now = opts.Now
+		})
+	}
+
+	var take func(state RateLimitState, now time.Time) (RateLimitState, bool, time.Duration)
+	switch opts.Algorithm {
+	case TokenBucket:
+		take = opts.takeTokenBucket
+	case SlidingWindow:
+		take = opts.takeSlidingWindow
+	case LeakyBucket:
+		take = opts.takeLeakyBucket
+	default:
+		panic("unknown rate limit algorithm")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			now := opts.Now()
+
+			var allowed bool
+			var wait time.Duration
+			err := opts.Store.Update(r.Context(), opts.Key(r), func(state RateLimitState) RateLimitState {
+				state, allowed, wait = take(state, now)
+				return state
+			})
+			if err != nil {
+				http.Error(w, "error checking rate limit", http.StatusInternalServerError)
+				return
+			}
+
+			if !allowed {
+				SetRetryAfter(w, wait)
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+
+			// Only LeakyBucket has a wait for allowed requests
+			if wait > 0 {
+				timer := time.NewTimer(wait)
+				defer timer.Stop()
+				select {
+				case <-timer.C:
+				case <-r.Context().Done():
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// idleTimeout after which the state for a key is the same as the zero state, for all algorithms:
+// a full token bucket, a sliding window with two empty windows, or a drained leaky bucket.
+func (o RateLimitOptions) idleTimeout() time.Duration {
+	return seconds(float64(o.Burst)/o.rate()) + 2*o.Period
+}
+
+// rate in requests per second.
+func (o RateLimitOptions) rate() float64 {
+	return float64(o.Limit) / o.Period.Seconds()
+}
+
+// takeTokenBucket keeps the number of tokens in Level, and the time of the last refill in Time.
+func (o RateLimitOptions) takeTokenBucket(state RateLimitState, now time.Time) (RateLimitState, bool, time.Duration) {
+	if state.Time.IsZero() {
+		state.Level = float64(o.Burst)
+	} else if elapsed := now.Sub(state.Time).Seconds(); elapsed > 0 {
+		state.Level = math.Min(float64(o.Burst), state.Level+elapsed*o.rate())
+	}
+	if now.After(state.Time) {
+		state.Time = now
+	}
+
+	if state.Level < 1 {
+		return state, false, seconds((1 - state.Level) / o.rate())
+	}
+	state.Level--
+	return state, true, 0
+}
+
+// takeSlidingWindow keeps the count of the previous window in Level, the count of the current window in Count,
+// and the start of the current window in Time.
+func (o RateLimitOptions) takeSlidingWindow(state RateLimitState, now time.Time) (RateLimitState, bool, time.Duration) {
+	start := now.Truncate(o.Period)
+	switch {
+	case state.Time.Equal(start):
+	case state.Time.Add(o.Period).Equal(start):
+		state.Level, state.Count, state.Time = state.Count, 0, start
+	default:
+		state.Level, state.Count, state.Time = 0, 0, start
+	}
+
+	elapsed := now.Sub(start).Seconds()
+	period := o.Period.Seconds()
+	weight := 1 - elapsed/period
+
+	if state.Level*weight+state.Count+1 <= float64(o.Limit) {
+		state.Count++
+		return state, true, 0
+	}
+
+	// Wait until the previous window's weight has dropped enough, or for the next window
+	wait := period - elapsed
+	if state.Level > 0 && state.Count+1 <= float64(o.Limit) {
+		wait = period*(1-(float64(o.Limit)-state.Count-1)/state.Level) - elapsed
+	}
+	return state, false, seconds(wait)
+}
+
+// takeLeakyBucket keeps the time when the bucket has drained the last accepted request in Time.
+func (o RateLimitOptions) takeLeakyBucket(state RateLimitState, now time.Time) (RateLimitState, bool, time.Duration) {
+	interval := seconds(1 / o.rate())
+
+	next := state.Time
+	if next.Before(now) {
+		next = now
+	}
+
+	wait := next.Sub(now)
+	if capacity := time.Duration(o.Burst) * interval; wait > capacity {
+		return state, false, wait - capacity
+	}
+
+	state.Time = next.Add(interval)
+	return state, true, wait
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// clientIP from RealIPFromContext, or else the host part of http.Request.RemoteAddr.
+func clientIP(r *http.Request) string {
+	if ip := RealIPFromContext(r.Context()); ip != "" {
+		return ip
+	}
+	return remoteHost(r)
+}
+
+// remoteHost is the host part of http.Request.RemoteAddr.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// MemoryRateLimitStoreOptions for NewMemoryRateLimitStore.
+type MemoryRateLimitStoreOptions struct {
+	// IdleTimeout after which a key that hasn't been updated is evicted, to bound memory use.
+	// Set it long enough that an evicted key's state would be the same as a new key's, or clients
+	// get a fresh limit early. Zero means keys are never evicted, which is the default.
+	IdleTimeout time.Duration
+
+	// Now is the clock used. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// MemoryRateLimitStore is an in-memory RateLimitStore, useful for tests and single-instance apps.
+type MemoryRateLimitStore struct {
+	lock      sync.Mutex
+	opts      MemoryRateLimitStoreOptions
+	states    map[string]memoryRateLimitState
+	lastSweep time.Time
+}
+
+type memoryRateLimitState struct {
+	state   RateLimitState
+	updated time.Time
+}
+
+// NewMemoryRateLimitStore returns an empty MemoryRateLimitStore.
+// Options can be set with the options functions, see MemoryRateLimitStoreOptions.
+func NewMemoryRateLimitStore(optsFuncs ...func(opts *MemoryRateLimitStoreOptions)) *MemoryRateLimitStore {
+	var opts MemoryRateLimitStoreOptions
+	for _, f := range optsFuncs {
+		f(&opts)
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	return &MemoryRateLimitStore{
+		opts:      opts,
+		states:    map[string]memoryRateLimitState{},
+		lastSweep: opts.Now(),
+	}
+}
+
+// Update satisfies RateLimitStore.
+// If there's an IdleTimeout, idle keys are evicted in a sweep at most once per IdleTimeout, during an Update.
+func (m *MemoryRateLimitStore) Update(ctx context.Context, key string, f func(state RateLimitState) RateLimitState) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := m.opts.Now()
+	if m.opts.IdleTimeout > 0 && now.Sub(m.lastSweep) >= m.opts.IdleTimeout {
+		for k, s := range m.states {
+			if now.Sub(s.updated) >= m.opts.IdleTimeout {
+				delete(m.states, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	m.states[key] = memoryRateLimitState{state: f(m.states[key].state), updated: now}
+	return nil
+}
+
+// Len is the number of keys in the store.
+func (m *MemoryRateLimitStore) Len() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return len(m.states)
+}
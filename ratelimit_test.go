@@ -0,0 +1,177 @@
+package httph_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestRateLimit(t *testing.T) {
+	newHandler := func(opts httph.RateLimitOptions) (http.Handler, *time.Time) {
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		opts.Now = func() time.Time {
+			return now
+		}
+		h := httph.RateLimit(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		return h, &now
+	}
+
+	request := func(h http.Handler, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("token bucket allows a burst and then refills steadily", func(t *testing.T) {
+		h, now := newHandler(httph.RateLimitOptions{Limit: 1, Period: time.Second, Burst: 3})
+
+		for i := 0; i < 3; i++ {
+			is.Equal(t, http.StatusOK, request(h, "1.2.3.4:1234").Code)
+		}
+
+		res := request(h, "1.2.3.4:1234")
+		is.Equal(t, http.StatusTooManyRequests, res.Code)
+		is.Equal(t, "1", res.Header().Get("Retry-After"))
+
+		is.Equal(t, http.StatusOK, request(h, "5.6.7.8:1234").Code)
+
+		*now = now.Add(time.Second)
+		is.Equal(t, http.StatusOK, request(h, "1.2.3.4:5678").Code)
+		is.Equal(t, http.StatusTooManyRequests, request(h, "1.2.3.4:5678").Code)
+	})
+
+	t.Run("sliding window allows the limit in any window", func(t *testing.T) {
+		h, now := newHandler(httph.RateLimitOptions{Algorithm: httph.SlidingWindow, Limit: 2, Period: 10 * time.Second})
+
+		is.Equal(t, http.StatusOK, request(h, "1.2.3.4:1234").Code)
+		is.Equal(t, http.StatusOK, request(h, "1.2.3.4:1234").Code)
+		res := request(h, "1.2.3.4:1234")
+		is.Equal(t, http.StatusTooManyRequests, res.Code)
+		is.Equal(t, "10", res.Header().Get("Retry-After"))
+
+		// Halfway into the next window, the previous window still counts for half
+		*now = now.Add(15 * time.Second)
+		is.Equal(t, http.StatusOK, request(h, "1.2.3.4:1234").Code)
+		res = request(h, "1.2.3.4:1234")
+		is.Equal(t, http.StatusTooManyRequests, res.Code)
+		is.Equal(t, "5", res.Header().Get("Retry-After"))
+	})
+
+	t.Run("leaky bucket delays requests and rejects when full", func(t *testing.T) {
+		var served []time.Time
+		now := time.Now()
+		h := httph.RateLimit(httph.RateLimitOptions{
+			Algorithm: httph.LeakyBucket,
+			Limit:     1,
+			Period:    20 * time.Millisecond,
+			Burst:     1,
+			Now: func() time.Time {
+				return now
+			},
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			served = append(served, time.Now())
+		}))
+
+		start := time.Now()
+		is.Equal(t, http.StatusOK, request(h, "1.2.3.4:1234").Code)
+		is.True(t, time.Since(start) < 10*time.Millisecond)
+
+		is.Equal(t, http.StatusOK, request(h, "1.2.3.4:1234").Code)
+		is.True(t, time.Since(start) >= 20*time.Millisecond)
+
+		res := request(h, "1.2.3.4:1234")
+		is.Equal(t, http.StatusTooManyRequests, res.Code)
+		is.Equal(t, "1", res.Header().Get("Retry-After"))
+		is.Equal(t, 2, len(served))
+	})
+
+	t.Run("uses a custom key", func(t *testing.T) {
+		h, _ := newHandler(httph.RateLimitOptions{Limit: 1, Period: time.Second, Key: func(r *http.Request) string {
+			return r.Header.Get("X-API-Key")
+		}})
+
+		is.Equal(t, http.StatusOK, request(h, "1.2.3.4:1234").Code)
+		is.Equal(t, http.StatusTooManyRequests, request(h, "5.6.7.8:1234").Code)
+	})
+
+	t.Run("uses the client IP from RealIP by default", func(t *testing.T) {
+		h, _ := newHandler(httph.RateLimitOptions{Limit: 1, Period: time.Second})
+		h = httph.RealIP(httph.RealIPOptions{TrustedProxies: []string{"10.0.0.0/8"}})(h)
+
+		requestVia := func(client string) int {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			req.Header.Set("X-Forwarded-For", client)
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+			return res.Code
+		}
+
+		is.Equal(t, http.StatusOK, requestVia("1.2.3.4"))
+		is.Equal(t, http.StatusTooManyRequests, requestVia("1.2.3.4"))
+		is.Equal(t, http.StatusOK, requestVia("5.6.7.8"))
+	})
+
+	t.Run("panics on invalid options", func(t *testing.T) {
+		defer func() {
+			is.True(t, recover() != nil)
+		}()
+		httph.RateLimit(httph.RateLimitOptions{})
+	})
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	t.Run("evicts idle keys", func(t *testing.T) {
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		store := httph.NewMemoryRateLimitStore(func(opts *httph.MemoryRateLimitStoreOptions) {
+			opts.IdleTimeout = time.Minute
+			opts.Now = func() time.Time {
+				return now
+			}
+		})
+
+		update := func(key string) httph.RateLimitState {
+			var current httph.RateLimitState
+			err := store.Update(context.Background(), key, func(state httph.RateLimitState) httph.RateLimitState {
+				current = state
+				state.Count++
+				return state
+			})
+			is.NotError(t, err)
+			return current
+		}
+
+		update("a")
+		update("b")
+		is.Equal(t, 2, store.Len())
+
+		now = now.Add(30 * time.Second)
+		is.Equal(t, float64(1), update("a").Count)
+		is.Equal(t, 2, store.Len())
+
+		now = now.Add(40 * time.Second)
+		update("c")
+		is.Equal(t, 2, store.Len())
+		is.Equal(t, float64(0), update("b").Count)
+		is.Equal(t, float64(2), update("a").Count)
+	})
+
+	t.Run("keeps keys without an idle timeout", func(t *testing.T) {
+		store := httph.NewMemoryRateLimitStore()
+		for _, key := range []string{"a", "b", "c"} {
+			err := store.Update(context.Background(), key, func(state httph.RateLimitState) httph.RateLimitState {
+				return state
+			})
+			is.NotError(t, err)
+		}
+		is.Equal(t, 3, store.Len())
+	})
+}
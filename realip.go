@@ -0,0 +1,134 @@
+package httph
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type realIPContextKey struct{}
+
+// RealIPOptions for the RealIP Middleware.
+type RealIPOptions struct {
+	// TrustedProxies are the IP addresses or CIDR ranges, like "10.0.0.0/8", of proxies in front of the app.
+	// If set, proxy headers are only used for requests from a trusted proxy, and the client IP is the rightmost
+	// address in Forwarded or X-Forwarded-For that isn't a trusted proxy, so clients can't spoof it.
+	// If not set, proxy headers from any request are used, and the client IP is the leftmost public address.
+	TrustedProxies []string
+}
+
+// RealIP is Middleware to set the client IP from proxy headers, for apps behind a load balancer or other proxy.
+// The IP is taken from the first of the Forwarded, X-Forwarded-For, and X-Real-IP headers with a valid IP address,
+// see RealIPOptions for which address is used. The standard Forwarded header is preferred, so a client can't spoof
+// an X-Forwarded-For header that a proxy only setting Forwarded passes on. Requests without a valid address in the headers keep the IP
+// from http.Request.RemoteAddr.
+// The IP is set as http.Request.RemoteAddr, without a port, and in the request context,
+// where it's available through RealIPFromContext.
+// Panics on invalid trusted proxies.
+func RealIP(opts RealIPOptions) Middleware {
+	var trusted []netip.Prefix
+	for _, p := range opts.TrustedProxies {
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			addr, err := netip.ParseAddr(p)
+			if err != nil {
+				panic("invalid trusted proxy " + p)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		trusted = append(trusted, prefix.Masked())
+	}
+
+	isTrusted := func(addr netip.Addr) bool {
+		for _, prefix := range trusted {
+			if prefix.Contains(addr.Unmap()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, _ := parseIP(remoteHost(r))
+
+			if len(trusted) == 0 || isTrusted(ip) {
+				if addrs := forwardedAddrs(r); len(addrs) > 0 {
+					ip = pickForwardedAddr(addrs, len(trusted) > 0, isTrusted)
+				} else if addr, ok := parseIP(r.Header.Get("X-Real-IP")); ok {
+					ip = addr
+				}
+			}
+
+			if ip.IsValid() {
+				r.RemoteAddr = ip.String()
+				r = r.WithContext(context.WithValue(r.Context(), realIPContextKey{}, ip.String()))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RealIPFromContext returns the client IP set by RealIP, or the empty string if there is none.
+func RealIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(realIPContextKey{}).(string)
+	return ip
+}
+
+// forwardedAddrs from the Forwarded header, or else the X-Forwarded-For header, in order from the client to the last proxy.
+// Invalid addresses are skipped.
+func forwardedAddrs(r *http.Request) []netip.Addr {
+	var addrs []netip.Addr
+	if values := r.Header.Values("Forwarded"); len(values) > 0 {
+		for _, e := range ParseForwarded(strings.Join(values, ",")) {
+			if addr, ok := parseIP(e.For); ok {
+				addrs = append(addrs, addr)
+			}
+		}
+		if len(addrs) > 0 {
+			return addrs
+		}
+	}
+
+	for _, v := range strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",") {
+		if addr, ok := parseIP(v); ok {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// pickForwardedAddr from addrs. With trusted proxies, it's the rightmost address that isn't trusted,
+// and otherwise the leftmost public address. Falls back to the leftmost address.
+func pickForwardedAddr(addrs []netip.Addr, hasTrusted bool, isTrusted func(netip.Addr) bool) netip.Addr {
+	if hasTrusted {
+		for i := len(addrs) - 1; i >= 0; i-- {
+			if !isTrusted(addrs[i]) {
+				return addrs[i]
+			}
+		}
+		return addrs[0]
+	}
+
+	for _, addr := range addrs {
+		if !addr.IsPrivate() && !addr.IsLoopback() && !addr.IsLinkLocalUnicast() && !addr.IsUnspecified() {
+			return addr
+		}
+	}
+	return addrs[0]
+}
+
+// parseIP parses an IP address, optionally with a port and IPv6 brackets, like "192.0.2.1:4711" or "[2001:db8::1]".
+func parseIP(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
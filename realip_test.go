@@ -0,0 +1,91 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestRealIP(t *testing.T) {
+	request := func(opts httph.RealIPOptions, remoteAddr string, headers map[string]string) (string, string) {
+		var remote, fromContext string
+		h := httph.RealIP(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			remote = r.RemoteAddr
+			fromContext = httph.RealIPFromContext(r.Context())
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		return remote, fromContext
+	}
+
+	t.Run("uses the address from a single-hop X-Forwarded-For", func(t *testing.T) {
+		remote, fromContext := request(httph.RealIPOptions{}, "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.7"})
+
+		is.Equal(t, "203.0.113.7", remote)
+		is.Equal(t, "203.0.113.7", fromContext)
+	})
+
+	t.Run("uses the leftmost public address from a multi-hop X-Forwarded-For", func(t *testing.T) {
+		remote, _ := request(httph.RealIPOptions{}, "10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "192.168.1.2, 203.0.113.7, 198.51.100.1, 10.0.0.2"})
+
+		is.Equal(t, "203.0.113.7", remote)
+	})
+
+	t.Run("uses the Forwarded and X-Real-IP headers", func(t *testing.T) {
+		remote, _ := request(httph.RealIPOptions{}, "10.0.0.1:1234", map[string]string{"Forwarded": `for="[2001:db8::17]:4711"`})
+		is.Equal(t, "2001:db8::17", remote)
+
+		remote, _ = request(httph.RealIPOptions{}, "10.0.0.1:1234", map[string]string{"X-Real-IP": "203.0.113.7"})
+		is.Equal(t, "203.0.113.7", remote)
+	})
+
+	t.Run("keeps the remote address without valid headers", func(t *testing.T) {
+		remote, fromContext := request(httph.RealIPOptions{}, "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "nope"})
+
+		is.Equal(t, "10.0.0.1", remote)
+		is.Equal(t, "10.0.0.1", fromContext)
+	})
+
+	t.Run("uses the rightmost untrusted address with trusted proxies", func(t *testing.T) {
+		remote, _ := request(httph.RealIPOptions{TrustedProxies: []string{"10.0.0.0/8"}}, "10.0.0.1:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.66, 203.0.113.7, 10.0.0.2"})
+
+		is.Equal(t, "203.0.113.7", remote)
+	})
+
+	t.Run("prefers the Forwarded header over X-Forwarded-For", func(t *testing.T) {
+		remote, _ := request(httph.RealIPOptions{TrustedProxies: []string{"10.0.0.0/8"}}, "10.0.0.1:1234",
+			map[string]string{"Forwarded": "for=203.0.113.7", "X-Forwarded-For": "198.51.100.66"})
+
+		is.Equal(t, "203.0.113.7", remote)
+
+		remote, _ = request(httph.RealIPOptions{}, "10.0.0.1:1234",
+			map[string]string{"Forwarded": "for=unknown", "X-Forwarded-For": "198.51.100.66"})
+
+		is.Equal(t, "198.51.100.66", remote)
+	})
+
+	t.Run("ignores spoofed headers from untrusted clients", func(t *testing.T) {
+		remote, _ := request(httph.RealIPOptions{TrustedProxies: []string{"10.0.0.1"}}, "203.0.113.7:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.66", "X-Real-IP": "198.51.100.66"})
+
+		is.Equal(t, "203.0.113.7", remote)
+	})
+
+	t.Run("panics on invalid trusted proxies", func(t *testing.T) {
+		defer func() {
+			is.Equal(t, any("invalid trusted proxy nope"), recover())
+		}()
+		httph.RealIP(httph.RealIPOptions{TrustedProxies: []string{"nope"}})
+	})
+}
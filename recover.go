@@ -0,0 +1,36 @@
+package httph
+
+import (
+	"net/http"
+)
+
+// Recover is Middleware to recover from panics in the next handler, returning http.StatusInternalServerError.
+// If the panic value satisfies statusCodeGiver, like HTTPError, the given HTTP status code is returned instead,
+// with the error message if it's an error.
+// Panics with http.ErrAbortHandler are re-panicked, so the server can abort the response as intended.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			code := http.StatusInternalServerError
+			message := http.StatusText(code)
+			if rec, ok := rec.(statusCodeGiver); ok {
+				code = rec.StatusCode()
+				message = http.StatusText(code)
+				if err, ok := rec.(error); ok {
+					message = errorMessage(err)
+				}
+			}
+			http.Error(w, message, code)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
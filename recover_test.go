@@ -0,0 +1,66 @@
+package httph_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestRecover(t *testing.T) {
+	t.Run("returns internal server error on panic", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h := httph.Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(errors.New("oh no"))
+		}))
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusInternalServerError, res.Result().StatusCode)
+		is.Equal(t, "Internal Server Error", readBody(t, res))
+	})
+
+	t.Run("returns the status code of a panic value that satisfies statusCodeGiver", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h := httph.Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(httph.HTTPError{Code: http.StatusNotFound})
+		}))
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusNotFound, res.Result().StatusCode)
+		is.Equal(t, "Not Found", readBody(t, res))
+	})
+
+	t.Run("passes through when there is no panic", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h := httph.Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusAccepted, res.Result().StatusCode)
+	})
+
+	t.Run("re-panics on http.ErrAbortHandler", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		defer func() {
+			is.Equal(t, any(http.ErrAbortHandler), recover())
+		}()
+
+		h := httph.Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+		h.ServeHTTP(res, req)
+	})
+}
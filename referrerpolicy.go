@@ -0,0 +1,49 @@
+package httph
+
+import (
+	"net/http"
+	"strings"
+)
+
+// referrerPolicies are the valid Referrer-Policy tokens.
+var referrerPolicies = map[string]struct{}{
+	"no-referrer":                     {},
+	"no-referrer-when-downgrade":      {},
+	"origin":                          {},
+	"origin-when-cross-origin":        {},
+	"same-origin":                     {},
+	"strict-origin":                   {},
+	"strict-origin-when-cross-origin": {},
+	"unsafe-url":                      {},
+}
+
+// ReferrerPolicy is Middleware to set the Referrer-Policy header, to limit what's sent in the Referer header.
+// The policy defaults to "strict-origin-when-cross-origin" if empty. A comma-separated list of policies is allowed,
+// where browsers use the last one they support.
+// Panics on invalid policies.
+// See https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Referrer-Policy
+func ReferrerPolicy(policy string) Middleware {
+	if policy == "" {
+		policy = "strict-origin-when-cross-origin"
+	}
+	if !validReferrerPolicy(policy) {
+		panic("invalid referrer policy " + policy)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Referrer-Policy", policy)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// validReferrerPolicy returns whether the policy is a comma-separated list of valid policy tokens.
+func validReferrerPolicy(policy string) bool {
+	for _, token := range strings.Split(policy, ",") {
+		if _, ok := referrerPolicies[strings.TrimSpace(token)]; !ok {
+			return false
+		}
+	}
+	return true
+}
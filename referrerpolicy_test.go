@@ -0,0 +1,38 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestReferrerPolicy(t *testing.T) {
+	request := func(policy string) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+
+		h := httph.ReferrerPolicy(policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		h.ServeHTTP(res, req)
+		return res.Result().Header.Get("Referrer-Policy")
+	}
+
+	t.Run("sets the given policy", func(t *testing.T) {
+		is.Equal(t, "no-referrer", request("no-referrer"))
+		is.Equal(t, "no-referrer, strict-origin-when-cross-origin", request("no-referrer, strict-origin-when-cross-origin"))
+	})
+
+	t.Run("defaults to strict-origin-when-cross-origin", func(t *testing.T) {
+		is.Equal(t, "strict-origin-when-cross-origin", request(""))
+	})
+
+	t.Run("panics on an invalid policy", func(t *testing.T) {
+		defer func() {
+			is.Equal(t, any("invalid referrer policy no-referer"), recover())
+		}()
+		httph.ReferrerPolicy("no-referer")
+	})
+}
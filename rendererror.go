@@ -0,0 +1,124 @@
+package httph
+
+import (
+	"embed"
+	"errors"
+	"html/template"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//go:embed error.gohtml
+var errorFS embed.FS
+
+// ErrorTemplate is the HTML template used by RenderError.
+// It's executed with a struct with the fields Code (the HTTP status code), Title (the status text),
+// and Message (the error message). Replace it to customize the error page.
+var ErrorTemplate = template.Must(template.ParseFS(errorFS, "error.gohtml"))
+
+// RenderError writes err to the response in a format negotiated from the request Accept header:
+// JSON like in JSONHandler, an HTML page from ErrorTemplate, or plain text.
+// Plain text is the default if the Accept header is missing or ambiguous, like "*/*",
+// or doesn't accept any of the formats.
+// If the error satisfies the statusCodeGiver interface, the given HTTP status code is used,
+// otherwise http.StatusInternalServerError. A wrapped *ValidationError results in http.StatusBadRequest,
+// and its field messages are included in the response.
+// 5xx errors are logged if an error logger is set with SetErrorLogger.
+func RenderError(w http.ResponseWriter, r *http.Request, err error) {
+	var ve *ValidationError
+	isValidationError := errors.As(err, &ve)
+	code := errorStatusCode(err)
+	logError(r, code, err)
+
+	switch negotiate(r.Header.Get("Accept"), "text/plain", "application/json", "text/html") {
+	case "application/json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(code)
+		writeResponse(w, errorResponseFor(err))
+
+	case "text/html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(code)
+		message := errorMessage(err)
+		if isValidationError {
+			message = ve.Error()
+		}
+		// There's not much we can do about an error here, so ignore it
+		_ = ErrorTemplate.Execute(w, struct {
+			Code    int
+			Title   string
+			Message string
+		}{Code: code, Title: http.StatusText(code), Message: message})
+
+	default:
+		message := errorMessage(err)
+		if isValidationError {
+			message = strings.TrimSuffix(ve.lines(), "\n")
+		}
+		http.Error(w, message, code)
+	}
+}
+
+// negotiate the best of the offered media types from the Accept header.
+// Ties are resolved by the order of the offers, so the first offer is the default.
+// Returns the empty string if no offers are acceptable.
+// See https://www.rfc-editor.org/rfc/rfc9110#field.accept
+func negotiate(accept string, offers ...string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	type mediaRange struct {
+		typ, subtype string
+		q            float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		typ, subtype, _ := strings.Cut(mediaType, "/")
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, q: q})
+	}
+
+	var best string
+	var bestQ float64
+	for _, offer := range offers {
+		typ, subtype, _ := strings.Cut(offer, "/")
+
+		// The most specific matching range decides the quality
+		q, specificity := 0.0, -1
+		for _, mr := range ranges {
+			var s int
+			switch {
+			case mr.typ == typ && mr.subtype == subtype:
+				s = 2
+			case mr.typ == typ && mr.subtype == "*":
+				s = 1
+			case mr.typ == "*" && mr.subtype == "*":
+				s = 0
+			default:
+				continue
+			}
+			if s > specificity {
+				q, specificity = mr.q, s
+			}
+		}
+
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,80 @@
+package httph_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestRenderError(t *testing.T) {
+	render := func(accept string, err error) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		res := httptest.NewRecorder()
+		httph.RenderError(res, req, err)
+		return res
+	}
+
+	t.Run("renders plain text by default", func(t *testing.T) {
+		for _, accept := range []string{"", "*/*", "image/png", "text/*"} {
+			res := render(accept, errors.New("oh no"))
+
+			is.Equal(t, http.StatusInternalServerError, res.Code)
+			is.Equal(t, "text/plain; charset=utf-8", res.Header().Get("Content-Type"))
+			is.Equal(t, "oh no", readBody(t, res))
+		}
+	})
+
+	t.Run("renders JSON when accepted", func(t *testing.T) {
+		res := render("application/json", httph.HTTPError{Code: http.StatusNotFound})
+
+		is.Equal(t, http.StatusNotFound, res.Code)
+		is.Equal(t, "application/json", res.Header().Get("Content-Type"))
+		is.Equal(t, `{"Error":"Not Found"}`, readBody(t, res))
+	})
+
+	t.Run("renders HTML when accepted", func(t *testing.T) {
+		res := render("text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", httph.HTTPError{Code: http.StatusNotFound})
+
+		is.Equal(t, http.StatusNotFound, res.Code)
+		is.Equal(t, "text/html; charset=utf-8", res.Header().Get("Content-Type"))
+		body := readBody(t, res)
+		is.True(t, strings.Contains(body, "<title>404 Not Found</title>"))
+		is.True(t, strings.Contains(body, "<p>Not Found</p>"))
+	})
+
+	t.Run("prefers the format with the highest quality", func(t *testing.T) {
+		res := render("text/html;q=0.5, application/json", errors.New("oh no"))
+		is.Equal(t, "application/json", res.Header().Get("Content-Type"))
+
+		res = render("application/*;q=0.1, text/html", errors.New("oh no"))
+		is.Equal(t, "text/html; charset=utf-8", res.Header().Get("Content-Type"))
+	})
+
+	t.Run("escapes the error message in HTML", func(t *testing.T) {
+		res := render("text/html", errors.New("<script>"))
+
+		is.True(t, strings.Contains(readBody(t, res), "<p>&lt;script&gt;</p>"))
+	})
+
+	t.Run("renders validation errors with field messages", func(t *testing.T) {
+		var ve httph.ValidationError
+		ve.Add("name", "is required")
+
+		res := render("application/json", &ve)
+		is.Equal(t, http.StatusBadRequest, res.Code)
+		is.Equal(t, `{"Error":"invalid request","Fields":{"name":"is required"}}`, readBody(t, res))
+
+		res = render("", &ve)
+		is.Equal(t, http.StatusBadRequest, res.Code)
+		is.Equal(t, "name: is required", readBody(t, res))
+	})
+}
@@ -0,0 +1,72 @@
+package httph
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+type requestIDContextKey struct{}
+
+// RequestIDOptions for the RequestID Middleware.
+type RequestIDOptions struct {
+	// Header to read the incoming request ID from, and to set in the response. Defaults to "X-Request-ID".
+	Header string
+
+	// Generate a new request ID. Defaults to 16 random bytes in hex.
+	Generate func() string
+}
+
+// RequestID is Middleware to tag each request with an ID, for tracing.
+// The ID is taken from the request header if present, or generated otherwise.
+// Incoming IDs longer than 128 characters, or with characters that aren't printable ASCII,
+// are replaced with a generated one, so they're safe to log.
+// The ID is set in the response header, and in the request context, where it's available through RequestIDFromContext.
+func RequestID(opts RequestIDOptions) Middleware {
+	if opts.Header == "" {
+		opts.Header = "X-Request-ID"
+	}
+	if opts.Generate == nil {
+		opts.Generate = newRequestID
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(opts.Header)
+			if !validRequestID(id) {
+				id = opts.Generate()
+			}
+
+			w.Header().Set(opts.Header, id)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDContextKey{}, id)))
+		})
+	}
+}
+
+// RequestIDFromContext returns the request ID set by RequestID, or the empty string if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// validRequestID returns whether id is non-empty, at most 128 characters, and only printable ASCII.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
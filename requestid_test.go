@@ -0,0 +1,97 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestRequestID(t *testing.T) {
+	t.Run("generates a request ID and sets it in the context and response header", func(t *testing.T) {
+		var id string
+		h := httph.RequestID(httph.RequestIDOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id = httph.RequestIDFromContext(r.Context())
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.True(t, regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(id))
+		is.Equal(t, id, res.Result().Header.Get("X-Request-ID"))
+
+		first := id
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		is.True(t, first != id)
+	})
+
+	t.Run("passes through an incoming request ID", func(t *testing.T) {
+		var id string
+		h := httph.RequestID(httph.RequestIDOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id = httph.RequestIDFromContext(r.Context())
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Request-ID", "abc123")
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, "abc123", id)
+		is.Equal(t, "abc123", res.Result().Header.Get("X-Request-ID"))
+	})
+
+	t.Run("replaces invalid incoming request IDs", func(t *testing.T) {
+		h := httph.RequestID(httph.RequestIDOptions{Generate: func() string { return "generated" }})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		for _, incoming := range []string{"with space", "new\x7fline", strings.Repeat("a", 129)} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Request-ID", incoming)
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+
+			is.Equal(t, "generated", res.Result().Header.Get("X-Request-ID"))
+		}
+	})
+
+	t.Run("can use a custom header", func(t *testing.T) {
+		h := httph.RequestID(httph.RequestIDOptions{Header: "X-Correlation-ID"})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Correlation-ID", "abc123")
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, "abc123", res.Result().Header.Get("X-Correlation-ID"))
+		is.Equal(t, "", res.Result().Header.Get("X-Request-ID"))
+	})
+
+	t.Run("is included in JSONHandler error responses by default", func(t *testing.T) {
+		h := httph.RequestID(httph.RequestIDOptions{})(httph.JSONHandler(func(w http.ResponseWriter, r *http.Request, _ any) (any, error) {
+			return nil, httph.HTTPError{Code: http.StatusNotFound}
+		}, func(opts *httph.JSONHandlerOptions) {
+			opts.RequestIDField = "RequestID"
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Request-ID", "abc123")
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, `{"Error":"Not Found","RequestID":"abc123"}`, readBody(t, res))
+	})
+}
+
+func TestRequestIDFromContext(t *testing.T) {
+	t.Run("returns the empty string without a request ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		is.Equal(t, "", httph.RequestIDFromContext(req.Context()))
+	})
+}
@@ -0,0 +1,36 @@
+package httph
+
+import (
+	"net/http"
+)
+
+// RequirePOSTForForms is Middleware to catch form submissions that accidentally use GET,
+// which leaks the form data into URLs, browser history, and logs.
+// For the given paths, GET and HEAD requests with a query string return http.StatusMethodNotAllowed.
+// GET requests without a query string, like for rendering the form, and requests to other paths pass through.
+func RequirePOSTForForms(paths ...string) Middleware {
+	formPaths := map[string]struct{}{}
+	for _, p := range paths {
+		if p == "" {
+			panic("invalid path")
+		}
+		formPaths[p] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if _, ok := formPaths[r.URL.Path]; !ok || r.URL.RawQuery == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Allow", "GET, HEAD, POST")
+			http.Error(w, "form submissions must use POST", http.StatusMethodNotAllowed)
+		})
+	}
+}
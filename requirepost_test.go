@@ -0,0 +1,41 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestRequirePOSTForForms(t *testing.T) {
+	h := httph.RequirePOSTForForms("/signup")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		code   int
+	}{
+		{"rejects GET with a query string on a form path", http.MethodGet, "/signup?email=me@example.com", http.StatusMethodNotAllowed},
+		{"allows GET without a query string on a form path", http.MethodGet, "/signup", http.StatusOK},
+		{"allows POST on a form path", http.MethodPost, "/signup?next=/", http.StatusOK},
+		{"allows GET with a query string on other paths", http.MethodGet, "/search?q=hat", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.target, nil)
+			res := httptest.NewRecorder()
+
+			h.ServeHTTP(res, req)
+
+			is.Equal(t, test.code, res.Result().StatusCode)
+			if test.code == http.StatusMethodNotAllowed {
+				is.Equal(t, "GET, HEAD, POST", res.Result().Header.Get("Allow"))
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package httph
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+)
+
+// ResponseWriterWrapper wraps an http.ResponseWriter and records the status code and number of body bytes written,
+// so middleware can inspect them after the next handler has run.
+// It supports http.Flusher, http.Hijacker, and http.Pusher by delegating to the wrapped http.ResponseWriter,
+// and can be unwrapped by http.ResponseController.
+type ResponseWriterWrapper struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// WrapResponseWriter in a ResponseWriterWrapper.
+func WrapResponseWriter(w http.ResponseWriter) *ResponseWriterWrapper {
+	return &ResponseWriterWrapper{ResponseWriter: w}
+}
+
+// Status code written, or 0 if nothing has been written yet.
+// Informational 1xx status codes are not recorded.
+// A Write or Flush without a WriteHeader records an implicit http.StatusOK.
+func (w *ResponseWriterWrapper) Status() int {
+	return w.status
+}
+
+// BytesWritten to the response body.
+func (w *ResponseWriterWrapper) BytesWritten() int64 {
+	return w.bytes
+}
+
+// WriteHeader satisfies http.ResponseWriter.
+func (w *ResponseWriterWrapper) WriteHeader(code int) {
+	if w.status == 0 && code >= 200 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write satisfies http.ResponseWriter.
+func (w *ResponseWriterWrapper) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush satisfies http.Flusher.
+func (w *ResponseWriterWrapper) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError is like Flush but returns an error, and is used by http.ResponseController.
+func (w *ResponseWriterWrapper) FlushError() error {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack satisfies http.Hijacker.
+func (w *ResponseWriterWrapper) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Push satisfies http.Pusher. It returns http.ErrNotSupported if the wrapped http.ResponseWriter doesn't support it.
+func (w *ResponseWriterWrapper) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap the http.ResponseWriter for http.ResponseController.
+func (w *ResponseWriterWrapper) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// bufferedResponseWriter buffers the response status and body up to max bytes, for middleware that needs the whole
+// body before sending the headers, like ETag and ContentDigest. Larger responses, and responses flushed by the handler,
+// are streamed from then on. The middleware finishes the response after the handler has run, see buffered.
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	max         int64
+	buf         bytes.Buffer
+	code        int
+	passthrough bool
+}
+
+func (w *bufferedResponseWriter) WriteHeader(code int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	// Informational responses are sent right away
+	if code >= 100 && code < 200 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *bufferedResponseWriter) Write(p []byte) (int, error) {
+	if !w.passthrough && int64(w.buf.Len()+len(p)) > w.max {
+		if err := w.startPassthrough(); err != nil {
+			return 0, err
+		}
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+// Flush streams the response from now on.
+func (w *bufferedResponseWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError is like Flush but returns an error, and is used by http.ResponseController.
+func (w *bufferedResponseWriter) FlushError() error {
+	if err := w.startPassthrough(); err != nil {
+		return err
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap the http.ResponseWriter for http.ResponseController.
+func (w *bufferedResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// startPassthrough writes the status and anything buffered, and stops buffering.
+func (w *bufferedResponseWriter) startPassthrough() error {
+	if w.passthrough {
+		return nil
+	}
+	w.passthrough = true
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// buffered returns whether the response is still buffered, after the handler has run,
+// with the status code defaulting to http.StatusOK. If not, it has already been streamed.
+func (w *bufferedResponseWriter) buffered() bool {
+	if w.passthrough {
+		return false
+	}
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return true
+}
+
+// writeBuffered status code and body.
+func (w *bufferedResponseWriter) writeBuffered() {
+	w.ResponseWriter.WriteHeader(w.code)
+	// There's not much we can do about an error here, so ignore it
+	_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+}
@@ -0,0 +1,70 @@
+package httph_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestWrapResponseWriter(t *testing.T) {
+	t.Run("records an implicit OK status on write", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		w := httph.WrapResponseWriter(res)
+
+		is.Equal(t, 0, w.Status())
+
+		_, err := w.Write([]byte("Yo"))
+		is.NotError(t, err)
+
+		is.Equal(t, http.StatusOK, w.Status())
+		is.Equal(t, int64(2), w.BytesWritten())
+		is.Equal(t, "Yo", res.Body.String())
+	})
+
+	t.Run("records an explicit status and the bytes written", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		w := httph.WrapResponseWriter(res)
+
+		w.WriteHeader(http.StatusTeapot)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("I'm a "))
+		_, _ = w.Write([]byte("teapot"))
+
+		is.Equal(t, http.StatusTeapot, w.Status())
+		is.Equal(t, int64(12), w.BytesWritten())
+		is.Equal(t, http.StatusTeapot, res.Code)
+	})
+
+	t.Run("does not record informational status codes", func(t *testing.T) {
+		w := httph.WrapResponseWriter(httptest.NewRecorder())
+
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusAccepted)
+
+		is.Equal(t, http.StatusAccepted, w.Status())
+	})
+
+	t.Run("delegates flushing", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		w := httph.WrapResponseWriter(res)
+
+		w.Flush()
+
+		is.True(t, res.Flushed)
+		is.Equal(t, http.StatusOK, w.Status())
+	})
+
+	t.Run("returns not supported for push and hijack if the wrapped writer doesn't support them", func(t *testing.T) {
+		w := httph.WrapResponseWriter(httptest.NewRecorder())
+
+		is.True(t, errors.Is(w.Push("/app.js", nil), http.ErrNotSupported))
+		_, _, err := w.Hijack()
+		is.True(t, err != nil)
+		is.Equal(t, 0, w.Status())
+	})
+}
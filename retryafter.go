@@ -0,0 +1,26 @@
+package httph
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SetRetryAfter sets the Retry-After header to the duration in delta-seconds.
+// The spec only allows whole, non-negative seconds, so the duration is rounded up,
+// to not have clients retry too early, and negative durations become zero.
+// See https://www.rfc-editor.org/rfc/rfc9110#field.retry-after
+func SetRetryAfter(w http.ResponseWriter, d time.Duration) {
+	seconds := int64(math.Ceil(d.Seconds()))
+	if seconds < 0 {
+		seconds = 0
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+}
+
+// SetRetryAfterTime sets the Retry-After header to the time as an HTTP-date.
+// See https://www.rfc-editor.org/rfc/rfc9110#field.retry-after
+func SetRetryAfterTime(w http.ResponseWriter, t time.Time) {
+	w.Header().Set("Retry-After", t.UTC().Format(http.TimeFormat))
+}
@@ -0,0 +1,40 @@
+package httph_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestSetRetryAfter(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		expected string
+	}{
+		{time.Minute, "60"},
+		{1500 * time.Millisecond, "2"},
+		{time.Nanosecond, "1"},
+		{0, "0"},
+		{-time.Second, "0"},
+	}
+
+	for _, test := range tests {
+		t.Run("sets delta-seconds for "+test.d.String(), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			httph.SetRetryAfter(w, test.d)
+			is.Equal(t, test.expected, w.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestSetRetryAfterTime(t *testing.T) {
+	t.Run("sets an HTTP-date", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		httph.SetRetryAfterTime(w, time.Date(2030, 1, 2, 4, 5, 6, 0, time.FixedZone("CET", 60*60)))
+		is.Equal(t, "Wed, 02 Jan 2030 03:05:06 GMT", w.Header().Get("Retry-After"))
+	})
+}
@@ -0,0 +1,77 @@
+package httph
+
+import (
+	"net/http"
+)
+
+// SecureHeadersOptions for the SecureHeaders Middleware.
+type SecureHeadersOptions struct {
+	// ContentTypeOptions is the X-Content-Type-Options header value. Defaults to "nosniff".
+	ContentTypeOptions string
+
+	// FrameOptions is the X-Frame-Options header value. Defaults to "deny".
+	FrameOptions string
+
+	// ReferrerPolicy is the Referrer-Policy header value, see the ReferrerPolicy Middleware.
+	// Defaults to "strict-origin-when-cross-origin".
+	ReferrerPolicy string
+
+	// ContentSecurityPolicy options function, like for the ContentSecurityPolicy Middleware.
+	// Nil gives the default policy.
+	ContentSecurityPolicy func(opts *ContentSecurityPolicyOptions)
+
+	// HSTS options, like for the HSTS Middleware. Nil disables HSTS, which is the default,
+	// because it's hard to undo for clients that have seen it.
+	HSTS *HSTSOptions
+
+	// Disable individual headers.
+	DisableContentTypeOptions    bool
+	DisableFrameOptions          bool
+	DisableReferrerPolicy        bool
+	DisableContentSecurityPolicy bool
+}
+
+// SecureHeaders is Middleware to set a bundle of security headers with sensible defaults:
+// X-Content-Type-Options, X-Frame-Options, Referrer-Policy, Content-Security-Policy, and optionally
+// Strict-Transport-Security. See SecureHeadersOptions to override values or disable headers.
+func SecureHeaders(opts SecureHeadersOptions) Middleware {
+	if opts.ContentTypeOptions == "" {
+		opts.ContentTypeOptions = "nosniff"
+	}
+	if opts.FrameOptions == "" {
+		opts.FrameOptions = "deny"
+	}
+	if opts.ReferrerPolicy == "" {
+		opts.ReferrerPolicy = "strict-origin-when-cross-origin"
+	}
+	if !opts.DisableReferrerPolicy && !validReferrerPolicy(opts.ReferrerPolicy) {
+		panic("invalid referrer policy " + opts.ReferrerPolicy)
+	}
+
+	var middlewares []Middleware
+	if !opts.DisableContentSecurityPolicy {
+		middlewares = append(middlewares, ContentSecurityPolicy(opts.ContentSecurityPolicy))
+	}
+	if opts.HSTS != nil {
+		middlewares = append(middlewares, HSTS(*opts.HSTS))
+	}
+
+	return func(next http.Handler) http.Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !opts.DisableContentTypeOptions {
+				w.Header().Set("X-Content-Type-Options", opts.ContentTypeOptions)
+			}
+			if !opts.DisableFrameOptions {
+				w.Header().Set("X-Frame-Options", opts.FrameOptions)
+			}
+			if !opts.DisableReferrerPolicy {
+				w.Header().Set("Referrer-Policy", opts.ReferrerPolicy)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
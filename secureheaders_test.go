@@ -0,0 +1,66 @@
+package httph_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestSecureHeaders(t *testing.T) {
+	request := func(opts httph.SecureHeadersOptions) http.Header {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.TLS = &tls.ConnectionState{}
+		res := httptest.NewRecorder()
+
+		h := httph.SecureHeaders(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		h.ServeHTTP(res, req)
+		return res.Result().Header
+	}
+
+	t.Run("sets the default headers", func(t *testing.T) {
+		header := request(httph.SecureHeadersOptions{})
+
+		is.Equal(t, "nosniff", header.Get("X-Content-Type-Options"))
+		is.Equal(t, "deny", header.Get("X-Frame-Options"))
+		is.Equal(t, "strict-origin-when-cross-origin", header.Get("Referrer-Policy"))
+		is.Equal(t, "default-src 'none'; font-src 'self'; img-src 'self'; script-src 'self'; style-src 'self'",
+			header.Get("Content-Security-Policy"))
+		is.Equal(t, "", header.Get("Strict-Transport-Security"))
+	})
+
+	t.Run("can override values and enable HSTS", func(t *testing.T) {
+		header := request(httph.SecureHeadersOptions{
+			FrameOptions:   "sameorigin",
+			ReferrerPolicy: "no-referrer",
+			ContentSecurityPolicy: func(opts *httph.ContentSecurityPolicyOptions) {
+				opts.DefaultSrc = "'self'"
+			},
+			HSTS: &httph.HSTSOptions{IncludeSubdomains: true},
+		})
+
+		is.Equal(t, "sameorigin", header.Get("X-Frame-Options"))
+		is.Equal(t, "no-referrer", header.Get("Referrer-Policy"))
+		is.Equal(t, "default-src 'self'; font-src 'self'; img-src 'self'; script-src 'self'; style-src 'self'",
+			header.Get("Content-Security-Policy"))
+		is.Equal(t, "max-age=31536000; includeSubDomains", header.Get("Strict-Transport-Security"))
+	})
+
+	t.Run("can disable individual headers", func(t *testing.T) {
+		header := request(httph.SecureHeadersOptions{
+			DisableFrameOptions:          true,
+			DisableContentSecurityPolicy: true,
+		})
+
+		is.Equal(t, "nosniff", header.Get("X-Content-Type-Options"))
+		is.Equal(t, "strict-origin-when-cross-origin", header.Get("Referrer-Policy"))
+		_, ok := header["X-Frame-Options"]
+		is.True(t, !ok)
+		_, ok = header["Content-Security-Policy"]
+		is.True(t, !ok)
+	})
+}
@@ -0,0 +1,147 @@
+package httph
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// SequenceStore tracks the last seen sequence number per client key, for the Sequence Middleware.
+type SequenceStore interface {
+	// Advance the last seen sequence number for key to seq, if seq is greater, or if key hasn't been seen before.
+	// Returns whether it advanced. Must be safe for concurrent use, and check and set atomically.
+	Advance(ctx context.Context, key string, seq uint64) (bool, error)
+}
+
+// SequenceOptions for the Sequence Middleware.
+type SequenceOptions struct {
+	// Store for the last seen sequence numbers. Required.
+	Store SequenceStore
+
+	// KeyHeader identifies the client. Defaults to "X-Client-ID".
+	KeyHeader string
+
+	// SequenceHeader has the client's sequence number, a non-negative integer. Defaults to "X-Sequence".
+	SequenceHeader string
+}
+
+// Sequence is Middleware to reject out-of-order and replayed requests, using a client-provided sequence number
+// that must increase with every request from the same client key.
+// The first request seen for a key is accepted with any sequence number.
+// Requests with a missing key or invalid sequence number return http.StatusBadRequest,
+// and requests with a sequence number not greater than the last seen one return http.StatusConflict.
+// Note that the sequence number is used up before the next handler runs, so a client retrying a failed request
+// must use a new sequence number.
+func Sequence(opts SequenceOptions) Middleware {
+	if opts.Store == nil {
+		panic("no sequence store")
+	}
+	if opts.KeyHeader == "" {
+		opts.KeyHeader = "X-Client-ID"
+	}
+	if opts.SequenceHeader == "" {
+		opts.SequenceHeader = "X-Sequence"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(opts.KeyHeader)
+			if key == "" {
+				http.Error(w, "missing "+opts.KeyHeader+" header", http.StatusBadRequest)
+				return
+			}
+
+			seq, err := strconv.ParseUint(r.Header.Get(opts.SequenceHeader), 10, 64)
+			if err != nil {
+				http.Error(w, "invalid "+opts.SequenceHeader+" header", http.StatusBadRequest)
+				return
+			}
+
+			ok, err := opts.Store.Advance(r.Context(), key, seq)
+			if err != nil {
+				http.Error(w, "error checking sequence", http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				http.Error(w, "sequence number must increase", http.StatusConflict)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MemorySequenceStoreOptions for NewMemorySequenceStore.
+type MemorySequenceStoreOptions struct {
+	// MaxKeys in the store, after which the least recently advanced key is evicted, to bound memory use.
+	// An evicted key is new again, so its next sequence number is accepted whatever it is. Defaults to 10000.
+	MaxKeys int
+}
+
+// MemorySequenceStore is an in-memory SequenceStore, useful for tests and single-instance apps.
+// Keys come from a client-provided header, so only use it behind authentication, with keys of bounded cardinality,
+// like user IDs. Otherwise clients can evict each other's keys by sending many different ones, and replay requests.
+type MemorySequenceStore struct {
+	lock    sync.Mutex
+	maxKeys int
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type memorySequenceEntry struct {
+	key  string
+	last uint64
+}
+
+// NewMemorySequenceStore returns an empty MemorySequenceStore.
+// Options can be set with the options functions, see MemorySequenceStoreOptions.
+func NewMemorySequenceStore(optsFuncs ...func(opts *MemorySequenceStoreOptions)) *MemorySequenceStore {
+	var opts MemorySequenceStoreOptions
+	for _, f := range optsFuncs {
+		f(&opts)
+	}
+	if opts.MaxKeys <= 0 {
+		opts.MaxKeys = 10000
+	}
+
+	return &MemorySequenceStore{
+		maxKeys: opts.MaxKeys,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// Advance satisfies SequenceStore.
+func (m *MemorySequenceStore) Advance(ctx context.Context, key string, seq uint64) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if el, ok := m.entries[key]; ok {
+		e := el.Value.(*memorySequenceEntry)
+		if seq <= e.last {
+			return false, nil
+		}
+		e.last = seq
+		m.lru.MoveToFront(el)
+		return true, nil
+	}
+
+	m.entries[key] = m.lru.PushFront(&memorySequenceEntry{key: key, last: seq})
+	if m.lru.Len() > m.maxKeys {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memorySequenceEntry).key)
+	}
+	return true, nil
+}
+
+// Len is the number of keys in the store.
+func (m *MemorySequenceStore) Len() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return len(m.entries)
+}
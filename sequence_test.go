@@ -0,0 +1,78 @@
+package httph_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestSequence(t *testing.T) {
+	h := httph.Sequence(httph.SequenceOptions{Store: httph.NewMemorySequenceStore()})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(key, seq string) int {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		if key != "" {
+			req.Header.Set("X-Client-ID", key)
+		}
+		if seq != "" {
+			req.Header.Set("X-Sequence", seq)
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res.Result().StatusCode
+	}
+
+	t.Run("accepts the first seen sequence number and increasing ones", func(t *testing.T) {
+		is.Equal(t, http.StatusOK, do("a", "5"))
+		is.Equal(t, http.StatusOK, do("a", "6"))
+		is.Equal(t, http.StatusOK, do("a", "10"))
+	})
+
+	t.Run("rejects replayed and out-of-order sequence numbers with conflict", func(t *testing.T) {
+		is.Equal(t, http.StatusOK, do("b", "1"))
+		is.Equal(t, http.StatusConflict, do("b", "1"))
+		is.Equal(t, http.StatusOK, do("b", "3"))
+		is.Equal(t, http.StatusConflict, do("b", "2"))
+	})
+
+	t.Run("tracks keys separately", func(t *testing.T) {
+		is.Equal(t, http.StatusOK, do("c", "100"))
+		is.Equal(t, http.StatusOK, do("d", "1"))
+	})
+
+	t.Run("rejects missing key or invalid sequence with bad request", func(t *testing.T) {
+		is.Equal(t, http.StatusBadRequest, do("", "1"))
+		is.Equal(t, http.StatusBadRequest, do("e", ""))
+		is.Equal(t, http.StatusBadRequest, do("e", "-1"))
+		is.Equal(t, http.StatusBadRequest, do("e", "abc"))
+	})
+}
+
+func TestMemorySequenceStore(t *testing.T) {
+	t.Run("evicts the least recently advanced key over the max keys", func(t *testing.T) {
+		s := httph.NewMemorySequenceStore(func(opts *httph.MemorySequenceStoreOptions) {
+			opts.MaxKeys = 2
+		})
+
+		advance := func(key string, seq uint64) bool {
+			ok, err := s.Advance(context.Background(), key, seq)
+			is.NotError(t, err)
+			return ok
+		}
+
+		is.True(t, advance("a", 1))
+		is.True(t, advance("b", 1))
+		is.True(t, advance("a", 2))
+		is.True(t, advance("c", 1))
+		is.Equal(t, 2, s.Len())
+
+		is.True(t, !advance("a", 2))
+		is.True(t, advance("b", 1))
+	})
+}
@@ -0,0 +1,425 @@
+package httph
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionStore for server-side session data, used by SessionMiddleware.
+type SessionStore interface {
+	// Load session data by ID. If the session doesn't exist or has expired, return nil data and no error.
+	Load(ctx context.Context, id string) ([]byte, error)
+	// Save session data by ID, until the given expiry.
+	Save(ctx context.Context, id string, data []byte, expiry time.Time) error
+	// Delete session data by ID.
+	Delete(ctx context.Context, id string) error
+}
+
+// SessionOptions for SessionMiddleware.
+type SessionOptions struct {
+	// Secret used to sign the session cookie. Required.
+	Secret []byte
+
+	// Store for server-side sessions. If nil, the session data is stored in the signed cookie itself,
+	// which is only suitable for small amounts of data, and makes the data readable (but not changeable) by the client.
+	// Cookie sessions can't be revoked: Session.Destroy and Session.Regenerate only replace the cookie on the client,
+	// so a copy of an earlier cookie stays valid until it expires after MaxAge. Use a Store if you need revocation,
+	// like for logins.
+	Store SessionStore
+
+	// CookieName defaults to "session".
+	CookieName string
+
+	// MaxAge of the session since it was last saved. Defaults to 24 hours.
+	MaxAge time.Duration
+
+	// Path of the cookie. Defaults to "/".
+	Path string
+
+	// Domain of the cookie.
+	Domain string
+
+	// Secure sets the Secure attribute on the cookie, so it's only sent over HTTPS.
+	Secure bool
+
+	// SameSite attribute on the cookie. Defaults to http.SameSiteLaxMode.
+	SameSite http.SameSite
+}
+
+// Session data for a single client, available through SessionFromContext.
+// Values are stored as JSON, so anything that can be encoded as JSON can be stored.
+type Session struct {
+	id        string
+	oldID     string
+	values    map[string]json.RawMessage
+	isNew     bool
+	dirty     bool
+	destroyed bool
+	csrfKey   string
+	secret    []byte
+}
+
+type sessionContextKey struct{}
+
+// SessionFromContext returns the Session set by SessionMiddleware, or nil if there is none.
+func SessionFromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionContextKey{}).(*Session)
+	return s
+}
+
+// ID of the session.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// IsNew returns whether the session was created in this request.
+func (s *Session) IsNew() bool {
+	return s.isNew
+}
+
+// Get the value for key into v, which must be a pointer.
+// Returns false if there is no value for key, or it can't be decoded into v.
+func (s *Session) Get(key string, v any) bool {
+	raw, ok := s.values[key]
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(raw, v) == nil
+}
+
+// Set the value for key.
+func (s *Session) Set(key string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.values[key] = raw
+	s.dirty = true
+	return nil
+}
+
+// Delete the value for key.
+func (s *Session) Delete(key string) {
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.dirty = true
+	}
+}
+
+// Regenerate the session ID, keeping the values.
+// Call this when the privilege level changes, like on login, to protect against session fixation.
+// Without a SessionOptions.Store, the old session cookie isn't revoked, and stays valid until it expires.
+func (s *Session) Regenerate() {
+	if s.oldID == "" && !s.isNew {
+		s.oldID = s.id
+	}
+	s.id = newSessionID()
+	s.dirty = true
+}
+
+// Destroy the session and all its values, like on logout.
+// Without a SessionOptions.Store, only the cookie on the client is cleared, so a copy of it stays valid until it expires.
+func (s *Session) Destroy() {
+	s.destroyed = true
+	s.values = map[string]json.RawMessage{}
+	s.csrfKey = ""
+}
+
+// sessionData is what's stored in the cookie or the store.
+type sessionData struct {
+	ID      string `json:",omitempty"`
+	Expiry  time.Time
+	Values  map[string]json.RawMessage
+	CSRFKey string `json:",omitempty"`
+}
+
+// SessionMiddleware loads the session for each request and saves it before the response is written.
+// The session is available to handlers with SessionFromContext.
+// New sessions without values are not saved, so no cookie is set for clients that never get session data.
+// Expired, tampered, or unknown sessions are replaced with new ones.
+func SessionMiddleware(opts SessionOptions) Middleware {
+	if len(opts.Secret) == 0 {
+		panic("no session secret")
+	}
+	if opts.CookieName == "" {
+		opts.CookieName = "session"
+	}
+	if opts.MaxAge == 0 {
+		opts.MaxAge = 24 * time.Hour
+	}
+	if opts.Path == "" {
+		opts.Path = "/"
+	}
+	if opts.SameSite == 0 {
+		opts.SameSite = http.SameSiteLaxMode
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s, err := loadSession(r, opts)
+			if err != nil {
+				http.Error(w, "error loading session", http.StatusInternalServerError)
+				return
+			}
+
+			sw := &sessionWriter{ResponseWriter: w, save: func() error {
+				return saveSession(w, r, s, opts)
+			}}
+			next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), sessionContextKey{}, s)))
+			sw.saveOnce()
+		})
+	}
+}
+
+func loadSession(r *http.Request, opts SessionOptions) (*Session, error) {
+	s := &Session{
+		id:     newSessionID(),
+		values: map[string]json.RawMessage{},
+		isNew:  true,
+		secret: opts.Secret,
+	}
+
+	c, err := r.Cookie(opts.CookieName)
+	if err != nil {
+		return s, nil
+	}
+	value, ok := verifySigned(opts.Secret, c.Value)
+	if !ok {
+		return s, nil
+	}
+
+	var raw []byte
+	if opts.Store == nil {
+		raw, err = base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			return s, nil
+		}
+	} else {
+		raw, err = opts.Store.Load(r.Context(), value)
+		if err != nil {
+			return nil, err
+		}
+		if raw == nil {
+			return s, nil
+		}
+	}
+
+	var data sessionData
+	if err := json.Unmarshal(raw, &data); err != nil || time.Now().After(data.Expiry) {
+		return s, nil
+	}
+	if opts.Store != nil {
+		data.ID = value
+	}
+	if data.ID == "" {
+		return s, nil
+	}
+
+	s.id = data.ID
+	s.isNew = false
+	if data.Values != nil {
+		s.values = data.Values
+	}
+	s.csrfKey = data.CSRFKey
+	return s, nil
+}
+
+func saveSession(w http.ResponseWriter, r *http.Request, s *Session, opts SessionOptions) error {
+	cookie := &http.Cookie{
+		Name:     opts.CookieName,
+		Path:     opts.Path,
+		Domain:   opts.Domain,
+		Secure:   opts.Secure,
+		HttpOnly: true,
+		SameSite: opts.SameSite,
+	}
+
+	if s.oldID != "" && opts.Store != nil {
+		if err := opts.Store.Delete(r.Context(), s.oldID); err != nil {
+			return err
+		}
+	}
+
+	if s.destroyed {
+		if s.isNew && s.oldID == "" {
+			return nil
+		}
+		if opts.Store != nil {
+			if err := opts.Store.Delete(r.Context(), s.id); err != nil {
+				return err
+			}
+		}
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+		return nil
+	}
+
+	if !s.dirty || (s.isNew && len(s.values) == 0 && s.csrfKey == "") {
+		return nil
+	}
+
+	expiry := time.Now().Add(opts.MaxAge)
+	data := sessionData{Expiry: expiry, Values: s.values, CSRFKey: s.csrfKey}
+	if opts.Store == nil {
+		data.ID = s.id
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	value := s.id
+	if opts.Store == nil {
+		value = base64.RawURLEncoding.EncodeToString(raw)
+	} else if err := opts.Store.Save(r.Context(), s.id, raw, expiry); err != nil {
+		return err
+	}
+
+	cookie.Value = sign(opts.Secret, value)
+	cookie.Expires = expiry
+	cookie.MaxAge = int(opts.MaxAge.Seconds())
+	http.SetCookie(w, cookie)
+	return nil
+}
+
+// sessionWriter saves the session right before the response is written, so the cookie can still be set.
+// If saving fails, the response is replaced with http.StatusInternalServerError.
+type sessionWriter struct {
+	http.ResponseWriter
+	save   func() error
+	once   sync.Once
+	failed bool
+}
+
+func (w *sessionWriter) saveOnce() {
+	w.once.Do(func() {
+		if err := w.save(); err != nil {
+			w.failed = true
+			http.Error(w.ResponseWriter, "error saving session", http.StatusInternalServerError)
+		}
+	})
+}
+
+func (w *sessionWriter) WriteHeader(code int) {
+	w.saveOnce()
+	if w.failed {
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *sessionWriter) Write(p []byte) (int, error) {
+	w.saveOnce()
+	if w.failed {
+		return 0, errors.New("error saving session")
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush the underlying http.ResponseWriter, if it supports it.
+func (w *sessionWriter) Flush() {
+	w.saveOnce()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && !w.failed {
+		f.Flush()
+	}
+}
+
+// Unwrap the http.ResponseWriter for http.ResponseController.
+func (w *sessionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// MemorySessionStore is an in-memory SessionStore, useful for tests and single-instance apps.
+type MemorySessionStore struct {
+	lock     sync.Mutex
+	sessions map[string]memorySession
+}
+
+type memorySession struct {
+	data   []byte
+	expiry time.Time
+}
+
+// NewMemorySessionStore returns an empty MemorySessionStore.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: map[string]memorySession{}}
+}
+
+// Load satisfies SessionStore.
+func (m *MemorySessionStore) Load(ctx context.Context, id string) ([]byte, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(s.expiry) {
+		delete(m.sessions, id)
+		return nil, nil
+	}
+	return s.data, nil
+}
+
+// Save satisfies SessionStore.
+func (m *MemorySessionStore) Save(ctx context.Context, id string, data []byte, expiry time.Time) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.sessions[id] = memorySession{data: data, expiry: expiry}
+	return nil
+}
+
+// Delete satisfies SessionStore.
+func (m *MemorySessionStore) Delete(ctx context.Context, id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.sessions, id)
+	return nil
+}
+
+func newSessionID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// sign the value with an HMAC, giving "<value>.<signature>".
+func sign(secret []byte, value string) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(value))
+	return value + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifySigned checks a value signed with sign in constant time, and returns the value without the signature.
+func verifySigned(secret []byte, signed string) (string, bool) {
+	i := strings.LastIndexByte(signed, '.')
+	if i < 0 {
+		return "", false
+	}
+	value, signature := signed[:i], signed[i+1:]
+
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return "", false
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(value))
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return "", false
+	}
+	return value, true
+}
@@ -0,0 +1,133 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestSessionMiddleware(t *testing.T) {
+	secret := []byte("supersecret")
+
+	newHandler := func(store httph.SessionStore) http.Handler {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/set", func(w http.ResponseWriter, r *http.Request) {
+			s := httph.SessionFromContext(r.Context())
+			is.NotError(t, s.Set("name", r.URL.Query().Get("name")))
+			_, _ = w.Write([]byte(s.ID()))
+		})
+		mux.HandleFunc("/get", func(w http.ResponseWriter, r *http.Request) {
+			s := httph.SessionFromContext(r.Context())
+			var name string
+			if !s.Get("name", &name) {
+				name = "nobody"
+			}
+			_, _ = w.Write([]byte(name))
+		})
+		mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+			s := httph.SessionFromContext(r.Context())
+			s.Regenerate()
+			_, _ = w.Write([]byte(s.ID()))
+		})
+		mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+			httph.SessionFromContext(r.Context()).Destroy()
+		})
+		return httph.SessionMiddleware(httph.SessionOptions{Secret: secret, Store: store})(mux)
+	}
+
+	do := func(h http.Handler, path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res
+	}
+
+	for name, newStore := range map[string]func() httph.SessionStore{
+		"cookie": func() httph.SessionStore { return nil },
+		"store":  func() httph.SessionStore { return httph.NewMemorySessionStore() },
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Run("does not set a cookie for a new session without values", func(t *testing.T) {
+				res := do(newHandler(newStore()), "/get")
+				is.Equal(t, "nobody", readBody(t, res))
+				is.Equal(t, 0, len(res.Result().Cookies()))
+			})
+
+			t.Run("saves and loads session values", func(t *testing.T) {
+				h := newHandler(newStore())
+
+				res := do(h, "/set?name=Me")
+				cookies := res.Result().Cookies()
+				is.Equal(t, 1, len(cookies))
+				is.Equal(t, "session", cookies[0].Name)
+				is.True(t, cookies[0].HttpOnly)
+
+				res = do(h, "/get", cookies[0])
+				is.Equal(t, "Me", readBody(t, res))
+			})
+
+			t.Run("ignores a tampered cookie", func(t *testing.T) {
+				h := newHandler(newStore())
+
+				cookie := do(h, "/set?name=Me").Result().Cookies()[0]
+				cookie.Value = "x" + cookie.Value
+
+				res := do(h, "/get", cookie)
+				is.Equal(t, "nobody", readBody(t, res))
+			})
+
+			t.Run("regenerates the session ID but keeps values", func(t *testing.T) {
+				h := newHandler(newStore())
+
+				res := do(h, "/set?name=Me")
+				oldID := readBody(t, res)
+				oldCookie := res.Result().Cookies()[0]
+
+				res = do(h, "/login", oldCookie)
+				newID := readBody(t, res)
+				is.True(t, oldID != newID)
+				newCookie := res.Result().Cookies()[0]
+
+				res = do(h, "/get", newCookie)
+				is.Equal(t, "Me", readBody(t, res))
+			})
+
+			t.Run("destroys the session", func(t *testing.T) {
+				h := newHandler(newStore())
+
+				cookie := do(h, "/set?name=Me").Result().Cookies()[0]
+
+				res := do(h, "/logout", cookie)
+				cookies := res.Result().Cookies()
+				is.Equal(t, 1, len(cookies))
+				is.Equal(t, -1, cookies[0].MaxAge)
+			})
+		})
+	}
+
+	t.Run("deletes the old session from the store on regeneration", func(t *testing.T) {
+		store := httph.NewMemorySessionStore()
+		h := newHandler(store)
+
+		res := do(h, "/set?name=Me")
+		oldCookie := res.Result().Cookies()[0]
+		do(h, "/login", oldCookie)
+
+		res = do(h, "/get", oldCookie)
+		is.Equal(t, "nobody", readBody(t, res))
+	})
+
+	t.Run("panics without secret", func(t *testing.T) {
+		defer func() {
+			is.True(t, recover() != nil)
+		}()
+		httph.SessionMiddleware(httph.SessionOptions{})
+	})
+}
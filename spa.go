@@ -0,0 +1,137 @@
+package httph
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// SPAOptions for SPA.
+type SPAOptions struct {
+	// Index file served for routes. Defaults to "index.html".
+	Index string
+
+	// ExcludePrefix is a path prefix, like "/api/", for which http.StatusNotFound is returned instead of the index file,
+	// so mistyped API routes don't get HTML back. Empty by default.
+	ExcludePrefix string
+}
+
+// SPA serves a single-page app from fsys, like an embed.FS with the built frontend.
+//   - Files are served with the content type from DetectContentType.
+//   - Versioned paths, with a version in the name like "app.1a2b3c4d.js", are served from the file without the version,
+//     like with VersionedAssets, if no file with the version exists. Those are cached for a year as immutable,
+//     if the version looks like a hash, with at least 8 hex characters.
+//   - Other files and the index file are served with "Cache-Control: no-cache", so clients revalidate.
+//   - Precompressed variants next to a file, like "app.js.br" and "app.js.gz", are served if the client accepts them.
+//   - Other paths are routes in the app, and get the index file, except for paths with a file extension,
+//     which return http.StatusNotFound, so missing assets don't get HTML back.
+func SPA(fsys fs.FS, optsFuncs ...func(opts *SPAOptions)) http.Handler {
+	opts := &SPAOptions{
+		Index: "index.html",
+	}
+	for _, f := range optsFuncs {
+		f(opts)
+	}
+
+	return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			return HTTPError{Code: http.StatusMethodNotAllowed}
+		}
+
+		if opts.ExcludePrefix != "" && strings.HasPrefix(r.URL.Path, opts.ExcludePrefix) {
+			return HTTPError{Code: http.StatusNotFound}
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		versioned := versionedAssetMatcher.MatchString("/" + name)
+
+		if name != "" && name != opts.Index {
+			candidates := []string{name}
+			if versioned {
+				candidates = append(candidates, strings.TrimPrefix(versionedAssetMatcher.ReplaceAllString("/"+name, `$1$2`), "/"))
+			}
+			hashed := hashedAssetMatcher.MatchString("/" + name)
+			for i, candidate := range candidates {
+				// Only a file found through a stripped hash version is known to be fingerprinted
+				served, err := serveSPAFile(w, r, fsys, candidate, i == 1 && hashed)
+				if served || err != nil {
+					return err
+				}
+			}
+
+			if path.Ext(name) != "" {
+				return HTTPError{Code: http.StatusNotFound}
+			}
+		}
+
+		served, err := serveSPAFile(w, r, fsys, opts.Index, false)
+		if !served && err == nil {
+			return HTTPError{Code: http.StatusNotFound}
+		}
+		return err
+	})
+}
+
+// serveSPAFile with name from fsys if it's a regular file, or a precompressed variant of it.
+// Returns whether the file was served.
+func serveSPAFile(w http.ResponseWriter, r *http.Request, fsys fs.FS, name string, immutable bool) (bool, error) {
+	f, info, err := openFile(fsys, name)
+	if err != nil {
+		if httpErr, ok := asHTTPError(err); ok && httpErr.Code == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if info.IsDir() {
+		return false, nil
+	}
+
+	if immutable {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+
+	var varied bool
+	for _, encoding := range []struct{ name, ext string }{{"br", ".br"}, {"gzip", ".gz"}} {
+		cf, cinfo, err := openFile(fsys, name+encoding.ext)
+		if err != nil {
+			continue
+		}
+		if !varied {
+			w.Header().Add("Vary", "Accept-Encoding")
+			varied = true
+		}
+		if !acceptsEncoding(r, encoding.name) || cinfo.IsDir() {
+			_ = cf.Close()
+			continue
+		}
+		defer func() {
+			_ = cf.Close()
+		}()
+		w.Header().Set("Content-Encoding", encoding.name)
+		return true, serveFile(w, r, cf, cinfo, name)
+	}
+
+	return true, serveFile(w, r, f, info, name)
+}
+
+// acceptsEncoding returns whether the Accept-Encoding header of the request accepts the encoding.
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	for _, v := range r.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(v, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+				continue
+			}
+			q := strings.ReplaceAll(strings.TrimSpace(params), " ", "")
+			return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+		}
+	}
+	return false
+}
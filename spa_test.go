@@ -0,0 +1,108 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestSPA(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":       {Data: []byte("<p>App</p>")},
+		"app.js":           {Data: []byte("console.log('app')")},
+		"app.js.br":        {Data: []byte("brotli app")},
+		"style.abc123.css": {Data: []byte("body {}")},
+		"jquery.min.js":    {Data: []byte("jquery")},
+		"favicon.ico":      {Data: []byte("icon")},
+		"images/logo.svg":  {Data: []byte("<svg></svg>")},
+	}
+
+	h := httph.SPA(fsys, func(opts *httph.SPAOptions) {
+		opts.ExcludePrefix = "/api/"
+	})
+
+	request := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("serves the index file for the root and routes without caching", func(t *testing.T) {
+		for _, path := range []string{"/", "/index.html", "/users/123", "/images"} {
+			res := request(path, "")
+
+			is.Equal(t, http.StatusOK, res.Code)
+			is.Equal(t, "text/html; charset=utf-8", res.Header().Get("Content-Type"))
+			is.Equal(t, "no-cache", res.Header().Get("Cache-Control"))
+			is.Equal(t, "<p>App</p>", readBody(t, res))
+		}
+	})
+
+	t.Run("serves files with a version-like name as is without caching", func(t *testing.T) {
+		res := request("/style.abc123.css", "")
+
+		is.Equal(t, http.StatusOK, res.Code)
+		is.Equal(t, "text/css; charset=utf-8", res.Header().Get("Content-Type"))
+		is.Equal(t, "no-cache", res.Header().Get("Cache-Control"))
+		is.Equal(t, "body {}", readBody(t, res))
+
+		res = request("/jquery.min.js", "")
+
+		is.Equal(t, http.StatusOK, res.Code)
+		is.Equal(t, "no-cache", res.Header().Get("Cache-Control"))
+		is.Equal(t, "jquery", readBody(t, res))
+	})
+
+	t.Run("strips the version from versioned paths without a matching file", func(t *testing.T) {
+		res := request("/images/logo.1a2b3c4d.svg", "")
+
+		is.Equal(t, http.StatusOK, res.Code)
+		is.Equal(t, "image/svg+xml", res.Header().Get("Content-Type"))
+		is.Equal(t, "public, max-age=31536000, immutable", res.Header().Get("Cache-Control"))
+		is.Equal(t, "<svg></svg>", readBody(t, res))
+	})
+
+	t.Run("strips versions that don't look like a hash without caching", func(t *testing.T) {
+		for _, path := range []string{"/app.v2.js", "/app.latest.js", "/images/logo.def456.svg"} {
+			res := request(path, "")
+
+			is.Equal(t, http.StatusOK, res.Code)
+			is.Equal(t, "no-cache", res.Header().Get("Cache-Control"))
+		}
+	})
+
+	t.Run("serves precompressed variants when accepted", func(t *testing.T) {
+		res := request("/app.js", "gzip, br")
+
+		is.Equal(t, http.StatusOK, res.Code)
+		is.Equal(t, "br", res.Header().Get("Content-Encoding"))
+		is.Equal(t, "Accept-Encoding", res.Header().Get("Vary"))
+		is.Equal(t, "text/javascript; charset=utf-8", res.Header().Get("Content-Type"))
+		is.Equal(t, "brotli app", readBody(t, res))
+
+		res = request("/app.js", "gzip")
+
+		is.Equal(t, "", res.Header().Get("Content-Encoding"))
+		is.Equal(t, "Accept-Encoding", res.Header().Get("Vary"))
+		is.Equal(t, "console.log('app')", readBody(t, res))
+	})
+
+	t.Run("returns not found for missing files with an extension", func(t *testing.T) {
+		res := request("/missing.js", "")
+		is.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("returns not found for the excluded prefix", func(t *testing.T) {
+		res := request("/api/users", "")
+		is.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
@@ -0,0 +1,98 @@
+package httph
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SSEvent is a server-sent event for SSEHandler.
+// See https://html.spec.whatwg.org/multipage/server-sent-events.html
+type SSEvent struct {
+	// ID of the event, which the browser sends back in the Last-Event-ID header when reconnecting. Optional.
+	ID string
+
+	// Event type, which is "message" in the browser if empty. Optional.
+	Event string
+
+	// Data of the event. Multi-line data is sent as multiple data lines.
+	Data string
+
+	// Retry is the reconnection time for the browser, sent in milliseconds. Zero means it's not sent.
+	Retry time.Duration
+}
+
+var errInvalidSSEvent = errors.New("event ID and type can't contain newlines")
+
+// SSEHandler takes a function that is like a regular http.Handler, except it also receives a send function
+// to send server-sent events to the client, and returns an error.
+// The first send sets the Content-Type to text/event-stream, and disables caching and proxy buffering,
+// and each event is flushed to the client after it's written.
+// send returns the request context error if the client has disconnected, so the function can stop.
+// An error returned from the function before the first send is rendered like in RenderError.
+// After that, the status code and headers have been sent, so the error is only logged if it results in a 5xx
+// status code and an error logger is set with SetErrorLogger. Request context errors are ignored.
+func SSEHandler(h func(w http.ResponseWriter, r *http.Request, send func(event SSEvent) error) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		var started bool
+
+		send := func(event SSEvent) error {
+			if err := r.Context().Err(); err != nil {
+				return err
+			}
+			if strings.ContainsAny(event.ID, "\r\n") || strings.ContainsAny(event.Event, "\r\n") {
+				return errInvalidSSEvent
+			}
+
+			if !started {
+				started = true
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Header().Set("Cache-Control", "no-cache")
+				w.Header().Set("X-Accel-Buffering", "no")
+				w.WriteHeader(http.StatusOK)
+			}
+
+			if _, err := w.Write(formatSSEvent(event)); err != nil {
+				return err
+			}
+
+			if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+			return nil
+		}
+
+		err := h(w, r, send)
+		if err == nil || (r.Context().Err() != nil && errors.Is(err, r.Context().Err())) {
+			return
+		}
+		if !started {
+			RenderError(w, r, err)
+			return
+		}
+		logError(r, errorStatusCode(err), err)
+	}
+}
+
+// formatSSEvent in the event stream format, ending with an empty line.
+func formatSSEvent(event SSEvent) []byte {
+	var b strings.Builder
+	if event.ID != "" {
+		b.WriteString("id: " + event.ID + "\n")
+	}
+	if event.Event != "" {
+		b.WriteString("event: " + event.Event + "\n")
+	}
+	if event.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(event.Retry.Milliseconds(), 10) + "\n")
+	}
+	data := strings.ReplaceAll(strings.ReplaceAll(event.Data, "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return []byte(b.String())
+}
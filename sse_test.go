@@ -0,0 +1,100 @@
+package httph_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestSSEHandler(t *testing.T) {
+	t.Run("sends properly framed events and flushes", func(t *testing.T) {
+		var flushed bool
+		h := httph.SSEHandler(func(w http.ResponseWriter, r *http.Request, send func(httph.SSEvent) error) error {
+			if err := send(httph.SSEvent{ID: "1", Event: "greeting", Data: "Yo", Retry: 3 * time.Second}); err != nil {
+				return err
+			}
+			flushed = w.(*httptest.ResponseRecorder).Flushed
+			return send(httph.SSEvent{Data: "line 1\nline 2"})
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.True(t, flushed)
+		is.Equal(t, http.StatusOK, res.Code)
+		is.Equal(t, "text/event-stream", res.Header().Get("Content-Type"))
+		is.Equal(t, "no-cache", res.Header().Get("Cache-Control"))
+		is.Equal(t, "no", res.Header().Get("X-Accel-Buffering"))
+		is.Equal(t, "id: 1\nevent: greeting\nretry: 3000\ndata: Yo\n\ndata: line 1\ndata: line 2\n\n", res.Body.String())
+	})
+
+	t.Run("returns an error for event IDs and types with newlines", func(t *testing.T) {
+		var err error
+		h := httph.SSEHandler(func(w http.ResponseWriter, r *http.Request, send func(httph.SSEvent) error) error {
+			err = send(httph.SSEvent{Event: "a\nb", Data: "Yo"})
+			return nil
+		})
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		is.True(t, err != nil)
+		is.Equal(t, "", res.Body.String())
+	})
+
+	t.Run("stops when the request context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		res := httptest.NewRecorder()
+
+		var sent int
+		h := httph.SSEHandler(func(w http.ResponseWriter, r *http.Request, send func(httph.SSEvent) error) error {
+			for {
+				if err := send(httph.SSEvent{Data: "tick"}); err != nil {
+					return err
+				}
+				sent++
+				if sent == 2 {
+					cancel()
+				}
+			}
+		})
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, 2, sent)
+		is.Equal(t, "data: tick\n\ndata: tick\n\n", res.Body.String())
+	})
+
+	t.Run("renders an error returned before the first event", func(t *testing.T) {
+		h := httph.SSEHandler(func(w http.ResponseWriter, r *http.Request, send func(httph.SSEvent) error) error {
+			return httph.HTTPError{Code: http.StatusUnauthorized}
+		})
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		is.Equal(t, http.StatusUnauthorized, res.Code)
+		is.Equal(t, "Unauthorized\n", res.Body.String())
+	})
+
+	t.Run("does not render an error returned after the first event", func(t *testing.T) {
+		h := httph.SSEHandler(func(w http.ResponseWriter, r *http.Request, send func(httph.SSEvent) error) error {
+			_ = send(httph.SSEvent{Data: "Yo"})
+			return errors.New("oh no")
+		})
+
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		is.Equal(t, http.StatusOK, res.Code)
+		is.Equal(t, "data: Yo\n\n", res.Body.String())
+	})
+}
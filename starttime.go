@@ -0,0 +1,37 @@
+package httph
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type startTimeContextKey struct{}
+
+// StartTime is Middleware to record the request start time in the request context,
+// so other middleware and handlers can share a single authoritative value through Elapsed and StartTimeFromContext.
+// If a start time is already set further up the chain, it's kept.
+func StartTime(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(startTimeContextKey{}).(time.Time); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), startTimeContextKey{}, time.Now())))
+	})
+}
+
+// StartTimeFromContext returns the start time set by StartTime, and whether there is one.
+func StartTimeFromContext(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(startTimeContextKey{}).(time.Time)
+	return t, ok
+}
+
+// Elapsed time since the start time set by StartTime, or zero if there is none.
+func Elapsed(ctx context.Context) time.Duration {
+	t, ok := StartTimeFromContext(ctx)
+	if !ok {
+		return 0
+	}
+	return time.Since(t)
+}
@@ -0,0 +1,53 @@
+package httph_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestStartTime(t *testing.T) {
+	t.Run("records the start time and exposes elapsed time", func(t *testing.T) {
+		before := time.Now()
+		var start time.Time
+		var ok bool
+		var elapsed time.Duration
+		h := httph.StartTime(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start, ok = httph.StartTimeFromContext(r.Context())
+			time.Sleep(time.Millisecond)
+			elapsed = httph.Elapsed(r.Context())
+		}))
+
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		is.True(t, ok)
+		is.True(t, !start.Before(before))
+		is.True(t, elapsed >= time.Millisecond)
+	})
+
+	t.Run("keeps a start time already set", func(t *testing.T) {
+		var outer, inner time.Time
+		h := httph.StartTime(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			outer, _ = httph.StartTimeFromContext(r.Context())
+			httph.StartTime(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				inner, _ = httph.StartTimeFromContext(r.Context())
+			})).ServeHTTP(w, r)
+		}))
+
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		is.True(t, outer.Equal(inner))
+	})
+
+	t.Run("returns zero elapsed time without a start time", func(t *testing.T) {
+		_, ok := httph.StartTimeFromContext(context.Background())
+		is.True(t, !ok)
+		is.Equal(t, time.Duration(0), httph.Elapsed(context.Background()))
+	})
+}
@@ -0,0 +1,165 @@
+package httph
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MethodTimeoutsOptions for the MethodTimeouts Middleware.
+type MethodTimeoutsOptions struct {
+	// Timeouts by request method, like http.MethodGet. A zero timeout means no timeout for that method.
+	Timeouts map[string]time.Duration
+
+	// Default timeout for methods not in Timeouts. Zero means no timeout.
+	Default time.Duration
+}
+
+// MethodTimeouts is Middleware to set a request context deadline depending on the request method,
+// so for example reads can be given more time than writes.
+// Handlers must respect the context for the deadline to have an effect, by passing it to database calls and the like.
+// The middleware itself doesn't write a response when the deadline is exceeded.
+// Note that the server's own http.Server.WriteTimeout still applies on top of this, and when shorter,
+// cuts off the response before the context deadline, so set it at least as long as the longest timeout here.
+func MethodTimeouts(opts MethodTimeoutsOptions) Middleware {
+	timeouts := map[string]time.Duration{}
+	for method, d := range opts.Timeouts {
+		if d < 0 {
+			panic("invalid timeout for method " + method)
+		}
+		timeouts[strings.ToUpper(method)] = d
+	}
+	if opts.Default < 0 {
+		panic("invalid default timeout")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d, ok := timeouts[r.Method]
+			if !ok {
+				d = opts.Default
+			}
+			if d == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// TimeoutOptions for the Timeout Middleware.
+type TimeoutOptions struct {
+	// StatusCode for timed out requests. Defaults to http.StatusServiceUnavailable.
+	StatusCode int
+
+	// Message in the body of timed out requests. Defaults to the status text of StatusCode.
+	Message string
+}
+
+// Timeout is Middleware to bound the time the next handler has to respond.
+// The request context gets a deadline d from now, and if the handler hasn't returned by then,
+// the StatusCode and Message from the options are written instead, like with http.TimeoutHandler.
+// The response is buffered until the handler returns, so the handler can't write to the client concurrently
+// with the timeout response, and writes after the timeout return http.ErrHandlerTimeout.
+// That also means the response is not streamed, and flushing does nothing.
+// Panics in the handler are passed on to the caller.
+func Timeout(d time.Duration, optsFuncs ...func(opts *TimeoutOptions)) Middleware {
+	if d <= 0 {
+		panic("invalid timeout")
+	}
+
+	opts := &TimeoutOptions{
+		StatusCode: http.StatusServiceUnavailable,
+	}
+	for _, f := range optsFuncs {
+		f(opts)
+	}
+	if opts.Message == "" {
+		opts.Message = http.StatusText(opts.StatusCode)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			tw := &timeoutWriter{header: http.Header{}}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if rec := recover(); rec != nil {
+						panicked <- rec
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case rec := <-panicked:
+				panic(rec)
+
+			case <-done:
+				tw.lock.Lock()
+				defer tw.lock.Unlock()
+				for k, vs := range tw.header {
+					w.Header()[k] = vs
+				}
+				if tw.code == 0 {
+					tw.code = http.StatusOK
+				}
+				w.WriteHeader(tw.code)
+				// There's not much we can do about an error here, so ignore it
+				_, _ = w.Write(tw.buf.Bytes())
+
+			case <-ctx.Done():
+				tw.lock.Lock()
+				defer tw.lock.Unlock()
+				tw.timedOut = true
+				http.Error(w, opts.Message, opts.StatusCode)
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers the response for Timeout.
+type timeoutWriter struct {
+	lock     sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	code     int
+	timedOut bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.timedOut || w.code != 0 || code < 200 {
+		return
+	}
+	w.code = code
+}
+
+func (w *timeoutWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.buf.Write(p)
+}
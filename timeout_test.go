@@ -0,0 +1,131 @@
+package httph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestMethodTimeouts(t *testing.T) {
+	h := httph.MethodTimeouts(httph.MethodTimeoutsOptions{
+		Timeouts: map[string]time.Duration{
+			"get":           30 * time.Second,
+			http.MethodPost: 10 * time.Second,
+			http.MethodPut:  0,
+		},
+		Default: time.Second,
+	})
+
+	tests := []struct {
+		method  string
+		timeout time.Duration
+	}{
+		{http.MethodGet, 30 * time.Second},
+		{http.MethodPost, 10 * time.Second},
+		{http.MethodPut, 0},
+		{http.MethodDelete, time.Second},
+	}
+
+	for _, test := range tests {
+		t.Run("sets the deadline for "+test.method, func(t *testing.T) {
+			var deadline time.Time
+			var ok bool
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				deadline, ok = r.Context().Deadline()
+			})
+
+			req := httptest.NewRequest(test.method, "/", nil)
+			res := httptest.NewRecorder()
+			h(next).ServeHTTP(res, req)
+
+			if test.timeout == 0 {
+				is.True(t, !ok)
+				return
+			}
+			is.True(t, ok)
+			remaining := time.Until(deadline)
+			is.True(t, remaining <= test.timeout && remaining > test.timeout-time.Second)
+		})
+	}
+
+	t.Run("sets no deadline without a default", func(t *testing.T) {
+		var ok bool
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, ok = r.Context().Deadline()
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		httph.MethodTimeouts(httph.MethodTimeoutsOptions{})(next).ServeHTTP(res, req)
+
+		is.True(t, !ok)
+	})
+}
+
+func TestTimeout(t *testing.T) {
+	t.Run("passes through the response of a fast handler", func(t *testing.T) {
+		h := httph.Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Custom", "yo")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("Hello"))
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusCreated, res.Result().StatusCode)
+		is.Equal(t, "yo", res.Result().Header.Get("X-Custom"))
+		is.Equal(t, "Hello", res.Body.String())
+	})
+
+	t.Run("returns service unavailable for a slow handler", func(t *testing.T) {
+		written := make(chan error, 1)
+		h := httph.Timeout(time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			time.Sleep(10 * time.Millisecond)
+			_, err := w.Write([]byte("Hello"))
+			written <- err
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusServiceUnavailable, res.Result().StatusCode)
+		is.Equal(t, "Service Unavailable\n", res.Body.String())
+		is.Equal(t, http.ErrHandlerTimeout, <-written)
+	})
+
+	t.Run("can set a custom status code and message", func(t *testing.T) {
+		h := httph.Timeout(time.Millisecond, func(opts *httph.TimeoutOptions) {
+			opts.StatusCode = http.StatusGatewayTimeout
+			opts.Message = "too slow"
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+
+		is.Equal(t, http.StatusGatewayTimeout, res.Result().StatusCode)
+		is.Equal(t, "too slow\n", res.Body.String())
+	})
+
+	t.Run("passes on panics", func(t *testing.T) {
+		h := httph.Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("oh no")
+		}))
+
+		defer func() {
+			is.Equal(t, any("oh no"), recover())
+		}()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
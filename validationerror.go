@@ -0,0 +1,75 @@
+package httph
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ValidationError aggregates validation error messages by field name.
+// Return it from a Validate method, or from a JSONHandler function, to report field-level errors.
+// It satisfies statusCodeGiver with http.StatusBadRequest.
+//
+// In FormHandler, it's rendered as plain text with a line per field, like "name: is required".
+// In JSONHandler, it's rendered with the messages in the Fields object of the error response,
+// like {"Error":"invalid request","Fields":{"name":"is required"}}.
+type ValidationError struct {
+	fields   []string
+	messages map[string]string
+}
+
+// Add a message for field. Multiple messages for the same field are joined with "; ".
+func (e *ValidationError) Add(field, message string) {
+	if e.messages == nil {
+		e.messages = map[string]string{}
+	}
+	if m, ok := e.messages[field]; ok {
+		e.messages[field] = m + "; " + message
+		return
+	}
+	e.fields = append(e.fields, field)
+	e.messages[field] = message
+}
+
+// Err returns the ValidationError if it has any messages, otherwise nil.
+// Use it to return from a Validate method without returning a non-nil error interface holding an empty ValidationError.
+func (e *ValidationError) Err() error {
+	if e == nil || len(e.fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// FieldErrors returns the messages by field name.
+func (e *ValidationError) FieldErrors() map[string]string {
+	fieldErrors := make(map[string]string, len(e.messages))
+	for k, v := range e.messages {
+		fieldErrors[k] = v
+	}
+	return fieldErrors
+}
+
+// Error satisfies error, summarizing all field messages in the order the fields were added.
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	for i, field := range e.fields {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(field + ": " + e.messages[field])
+	}
+	return b.String()
+}
+
+// StatusCode satisfies statusCodeGiver.
+func (e *ValidationError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// lines with a field message on each, in the order the fields were added.
+func (e *ValidationError) lines() string {
+	var b strings.Builder
+	for _, field := range e.fields {
+		b.WriteString(field + ": " + e.messages[field] + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,33 @@
+package httph_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func TestValidationError(t *testing.T) {
+	t.Run("summarizes field messages in order", func(t *testing.T) {
+		var ve httph.ValidationError
+		ve.Add("name", "is required")
+		ve.Add("email", "must contain @")
+		ve.Add("name", "is too short")
+
+		is.Equal(t, "name: is required; is too short, email: must contain @", ve.Error())
+		is.Equal(t, http.StatusBadRequest, ve.StatusCode())
+		is.Equal(t, "is required; is too short", ve.FieldErrors()["name"])
+		is.Equal(t, "must contain @", ve.FieldErrors()["email"])
+		is.True(t, ve.Err() != nil)
+	})
+
+	t.Run("returns nil from Err when there are no messages", func(t *testing.T) {
+		var ve httph.ValidationError
+		is.NotError(t, ve.Err())
+
+		var nilVE *httph.ValidationError
+		is.NotError(t, nilVE.Err())
+	})
+}
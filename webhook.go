@@ -0,0 +1,177 @@
+package httph
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidWebhookSignature is returned from VerifyWebhook when the signature is missing, malformed, or doesn't match.
+var ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+// WebhookOptions for VerifyWebhook.
+// See GitHubWebhook and StripeWebhook for presets.
+type WebhookOptions struct {
+	// Header with the signature, for example "X-Hub-Signature-256".
+	Header string
+
+	// Hash used for the HMAC. Defaults to sha256.New.
+	Hash func() hash.Hash
+
+	// Prefix before the hex-encoded signature in the header, for example "sha256=".
+	// Not used if Timestamp is set.
+	Prefix string
+
+	// Timestamp signals that the header has the form "t=<unix seconds>,<SignatureKey>=<hex signature>,...",
+	// and that the signed payload is "<unix seconds>.<body>".
+	Timestamp bool
+
+	// SignatureKey in the timestamped header, for example "v1". Any matching signature is accepted.
+	SignatureKey string
+
+	// Tolerance for the timestamp age, to protect against replay attacks. Zero disables the check.
+	Tolerance time.Duration
+
+	// MaxSizeBytes of the request body. Defaults to 1 MiB.
+	MaxSizeBytes int64
+
+	// Now is used to check the timestamp. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// GitHubWebhook sets WebhookOptions for GitHub-style signatures in the X-Hub-Signature-256 header.
+// This is the default for VerifyWebhook.
+// See https://docs.github.com/en/webhooks/using-webhooks/validating-webhook-deliveries
+func GitHubWebhook(opts *WebhookOptions) {
+	opts.Header = "X-Hub-Signature-256"
+	opts.Hash = sha256.New
+	opts.Prefix = "sha256="
+	opts.Timestamp = false
+}
+
+// StripeWebhook sets WebhookOptions for Stripe-style timestamped signatures in the Stripe-Signature header,
+// with a five minute tolerance.
+// See https://docs.stripe.com/webhooks#verify-manually
+func StripeWebhook(opts *WebhookOptions) {
+	opts.Header = "Stripe-Signature"
+	opts.Hash = sha256.New
+	opts.Prefix = ""
+	opts.Timestamp = true
+	opts.SignatureKey = "v1"
+	opts.Tolerance = 5 * time.Minute
+}
+
+// VerifyWebhook reads the request body and verifies its HMAC signature with the given secret.
+// The signature scheme is GitHub-style by default, and can be changed with the options functions.
+// Signatures are compared in constant time.
+// The verified body is returned, and because reading consumes it, the request body is also replaced with a copy,
+// so it can be read again afterwards.
+// If the signature is missing, malformed, or doesn't match, the error wraps ErrInvalidWebhookSignature.
+func VerifyWebhook(r *http.Request, secret []byte, optsFuncs ...func(opts *WebhookOptions)) ([]byte, error) {
+	opts := &WebhookOptions{
+		MaxSizeBytes: 1 << 20,
+		Now:          time.Now,
+	}
+	GitHubWebhook(opts)
+	for _, f := range optsFuncs {
+		f(opts)
+	}
+
+	if len(secret) == 0 {
+		return nil, errors.New("no webhook secret")
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, opts.MaxSizeBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading webhook body: %w", err)
+	}
+	if int64(len(body)) > opts.MaxSizeBytes {
+		return nil, errors.New("webhook body too large")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := r.Header.Get(opts.Header)
+	if header == "" {
+		return nil, fmt.Errorf("%w: missing %v header", ErrInvalidWebhookSignature, opts.Header)
+	}
+
+	if !opts.Timestamp {
+		signature, ok := strings.CutPrefix(header, opts.Prefix)
+		if !ok {
+			return nil, fmt.Errorf("%w: missing prefix %v", ErrInvalidWebhookSignature, opts.Prefix)
+		}
+		if !validHMAC(opts.Hash, secret, body, signature) {
+			return nil, ErrInvalidWebhookSignature
+		}
+		return body, nil
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			timestamp = v
+		case opts.SignatureKey:
+			signatures = append(signatures, v)
+		}
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid timestamp", ErrInvalidWebhookSignature)
+	}
+	if len(signatures) == 0 {
+		return nil, fmt.Errorf("%w: no %v signatures", ErrInvalidWebhookSignature, opts.SignatureKey)
+	}
+
+	payload := make([]byte, 0, len(timestamp)+1+len(body))
+	payload = append(payload, timestamp...)
+	payload = append(payload, '.')
+	payload = append(payload, body...)
+
+	var valid bool
+	for _, signature := range signatures {
+		if validHMAC(opts.Hash, secret, payload, signature) {
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, ErrInvalidWebhookSignature
+	}
+
+	// Check the timestamp only after the signature, so the timestamp is known to be authentic
+	if opts.Tolerance > 0 {
+		age := opts.Now().Sub(time.Unix(seconds, 0))
+		if age > opts.Tolerance || age < -opts.Tolerance {
+			return nil, fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidWebhookSignature)
+		}
+	}
+
+	return body, nil
+}
+
+// validHMAC checks the hex-encoded signature against the HMAC of the payload in constant time.
+func validHMAC(h func() hash.Hash, secret, payload []byte, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(h, secret)
+	_, _ = mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
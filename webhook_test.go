@@ -0,0 +1,121 @@
+package httph_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maragudk/is"
+
+	"maragu.dev/httph"
+)
+
+func signWebhook(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	secret := []byte("secret")
+	body := `{"action":"opened"}`
+
+	t.Run("verifies a GitHub-style signature by default and returns the body", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("X-Hub-Signature-256", "sha256="+signWebhook("secret", body))
+
+		b, err := httph.VerifyWebhook(r, secret)
+		is.NotError(t, err)
+		is.Equal(t, body, string(b))
+
+		again, err := io.ReadAll(r.Body)
+		is.NotError(t, err)
+		is.Equal(t, body, string(again))
+	})
+
+	t.Run("errors on wrong signature", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("X-Hub-Signature-256", "sha256="+signWebhook("wrong", body))
+
+		_, err := httph.VerifyWebhook(r, secret)
+		is.True(t, errors.Is(err, httph.ErrInvalidWebhookSignature))
+	})
+
+	t.Run("errors on missing header", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+
+		_, err := httph.VerifyWebhook(r, secret, httph.GitHubWebhook)
+		is.True(t, errors.Is(err, httph.ErrInvalidWebhookSignature))
+	})
+
+	t.Run("errors on missing prefix", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("X-Hub-Signature-256", signWebhook("secret", body))
+
+		_, err := httph.VerifyWebhook(r, secret)
+		is.True(t, errors.Is(err, httph.ErrInvalidWebhookSignature))
+	})
+
+	t.Run("errors on body too large", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("X-Hub-Signature-256", "sha256="+signWebhook("secret", body))
+
+		_, err := httph.VerifyWebhook(r, secret, func(opts *httph.WebhookOptions) {
+			opts.MaxSizeBytes = 2
+		})
+		is.Equal(t, "webhook body too large", err.Error())
+	})
+
+	now := time.Unix(1700000000, 0)
+	stripe := func(opts *httph.WebhookOptions) {
+		httph.StripeWebhook(opts)
+		opts.Now = func() time.Time {
+			return now
+		}
+	}
+
+	t.Run("verifies a Stripe-style signature with any matching v1 signature", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Stripe-Signature", fmt.Sprintf("t=%v,v1=%v,v1=%v,v0=abc", now.Unix(),
+			signWebhook("old", fmt.Sprintf("%v.%v", now.Unix(), body)),
+			signWebhook("secret", fmt.Sprintf("%v.%v", now.Unix(), body))))
+
+		b, err := httph.VerifyWebhook(r, secret, stripe)
+		is.NotError(t, err)
+		is.Equal(t, body, string(b))
+	})
+
+	t.Run("errors on Stripe-style signature outside the tolerance", func(t *testing.T) {
+		then := now.Add(-6 * time.Minute).Unix()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Stripe-Signature", fmt.Sprintf("t=%v,v1=%v", then, signWebhook("secret", fmt.Sprintf("%v.%v", then, body))))
+
+		_, err := httph.VerifyWebhook(r, secret, stripe)
+		is.True(t, errors.Is(err, httph.ErrInvalidWebhookSignature))
+		is.Equal(t, "invalid webhook signature: timestamp outside tolerance", err.Error())
+	})
+
+	t.Run("errors on Stripe-style signature with tampered timestamp", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Stripe-Signature", fmt.Sprintf("t=%v,v1=%v", now.Unix()+1, signWebhook("secret", fmt.Sprintf("%v.%v", now.Unix(), body))))
+
+		_, err := httph.VerifyWebhook(r, secret, stripe)
+		is.True(t, errors.Is(err, httph.ErrInvalidWebhookSignature))
+	})
+
+	t.Run("errors on Stripe-style signature without timestamp", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Stripe-Signature", "v1="+signWebhook("secret", body))
+
+		_, err := httph.VerifyWebhook(r, secret, stripe)
+		is.True(t, errors.Is(err, httph.ErrInvalidWebhookSignature))
+	})
+}